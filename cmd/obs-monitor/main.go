@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/joepadmiraal/obs-monitor/internal/config"
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
 	"github.com/joepadmiraal/obs-monitor/internal/monitor"
-	"golang.org/x/term"
+	"github.com/joepadmiraal/obs-monitor/internal/password"
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
 )
 
 var (
@@ -18,72 +26,272 @@ var (
 	date    = "unknown"
 )
 
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// errReported is returned by run for errors that were already logged
+var errReported = errors.New("error already reported")
+
 func main() {
+	// run returns instead of exiting, so its deferred cleanup flushes and closes the writers
+	if err := run(); err != nil {
+		if !errors.Is(err, errReported) {
+			fmt.Printf("Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	defaults := config.Default()
+
 	versionFlag := flag.Bool("version", false, "Show version information")
-	password := flag.String("password", "", "OBS WebSocket password")
-	host := flag.String("host", "localhost", "OBS WebSocket host")
-	port := flag.String("port", "4455", "OBS WebSocket port")
+	configFile := flag.String("config", "", "YAML or TOML configuration file, flags override its values")
+	passwordFlag := flag.String("password", "", fmt.Sprintf("OBS WebSocket password, visible to other users, prefer -password-file or %s", password.EnvVar))
+	passwordFile := flag.String("password-file", "", "File holding the OBS WebSocket password")
+	host := flag.String("host", defaults.Connection.Host, "OBS WebSocket host")
+	port := flag.Int("port", defaults.Connection.Port, "OBS WebSocket port")
 	defaultCSVFile := fmt.Sprintf("obs-monitor-%s.csv", time.Now().Format("2006-01-02-15-04-05"))
 	csvFile := flag.String("csv", defaultCSVFile, "Optional CSV file to write metrics to")
-	metricIntervalMs := flag.Int("metric-interval", 1000, "Metric collection interval in milliseconds (default 1000ms)")
-	writerIntervalMs := flag.Int("writer-interval", 1000, "Writer interval in milliseconds (default 1000ms)")
+	metricIntervalMs := flag.Int("metric-interval", defaults.Intervals.MetricMs, "Metric collection interval in milliseconds (default 1000ms)")
+	writerIntervalMs := flag.Int("writer-interval", defaults.Intervals.WriterMs, "Writer interval in milliseconds (default 1000ms)")
+	prometheusListen := flag.String("prometheus-listen", "", "Address to serve Prometheus metrics on, e.g. :9090")
+	aggregate := flag.String("aggregate", "", fmt.Sprintf("Comma-separated aggregations of the RTTs and CPU usages per writer interval (%s or all)", strings.Join(aggregationNames(), ", ")))
+	probe := flag.String("probe", defaults.Probe, fmt.Sprintf("How to probe the stream server (%s), tcp measures the handshake with the ingest port for networks that drop ICMP", strings.Join(monitor.ProbeModes, ", ")))
+	wait := flag.Bool("wait", false, "Keep retrying to connect until OBS is started, instead of exiting")
+	daemonMode := flag.Bool("daemon", false, "Run as a service: wait for OBS, never prompt, log structured to stderr and notify systemd")
+	skipIdle := flag.Bool("skip-idle", false, "Only write rows while the stream is live")
+	silenceThreshold := flag.Float64("silence-threshold", defaults.Audio.SilenceThresholdDB, "Peak level in dBFS below which an audio input is quiet")
+	silenceSeconds := flag.Int("silence-seconds", defaults.Audio.SilenceSeconds, "Seconds an audio input must be quiet before it is flagged as silent")
+	var outputs stringList
+	flag.Var(&outputs, "output", fmt.Sprintf("Output to write metrics to as kind[:target], can be repeated (kinds: %s)", strings.Join(writer.Kinds(), ", ")))
+	var instances stringList
+	flag.Var(&instances, "instance", "OBS instance to monitor as name=host[:port], rows are tagged with the name, can be repeated")
+	var audioInputs stringList
+	flag.Var(&audioInputs, "audio-input", "Name of an OBS input whose audio levels are monitored, can be repeated")
+	var pingTargets stringList
+	flag.Var(&pingTargets, "ping-target", "Host to ping next to the stream server as name=host, the name prefixes its columns, can be repeated (default: google=google.com)")
 	flag.Parse()
 
 	if *versionFlag {
 		fmt.Printf("obs-monitor %s\n", version)
 		fmt.Printf("  commit: %s\n", commit)
 		fmt.Printf("  built:  %s\n", date)
-		os.Exit(0)
+		return nil
 	}
 
-	if *password == "" {
-		fmt.Print("Enter OBS WebSocket password: ")
-		passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
-		fmt.Println()
+	cfg := defaults
+	if *configFile != "" {
+		var err error
+		cfg, err = config.Load(*configFile)
 		if err != nil {
-			fmt.Printf("Error reading password: %v\n", err)
-			os.Exit(1)
+			return err
 		}
-		*password = string(passwordBytes)
 	}
 
-	if *metricIntervalMs > *writerIntervalMs {
-		fmt.Printf("Error: metric interval (%dms) cannot be higher than writer interval (%dms)\n", *metricIntervalMs, *writerIntervalMs)
-		os.Exit(1)
+	// Flags that are set explicitly override the configuration file
+	var errs []error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			cfg.Connection.Host = *host
+		case "port":
+			cfg.Connection.Port = *port
+		case "password-file":
+			cfg.Connection.PasswordFile = *passwordFile
+		case "wait":
+			cfg.Connection.Wait = *wait
+		case "metric-interval":
+			cfg.Intervals.MetricMs = *metricIntervalMs
+		case "writer-interval":
+			cfg.Intervals.WriterMs = *writerIntervalMs
+		case "aggregate":
+			cfg.Aggregate = []string{*aggregate}
+		case "probe":
+			cfg.Probe = *probe
+		case "output":
+			cfg.Writers = outputs
+		case "skip-idle":
+			cfg.SkipIdle = *skipIdle
+		case "audio-input":
+			cfg.Audio.Inputs = audioInputs
+		case "silence-threshold":
+			cfg.Audio.SilenceThresholdDB = *silenceThreshold
+		case "silence-seconds":
+			cfg.Audio.SilenceSeconds = *silenceSeconds
+		case "instance":
+			cfg.Instances = nil
+			for _, value := range instances {
+				inst, err := config.ParseInstance(value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("-instance: %w", err))
+					continue
+				}
+				cfg.Instances = append(cfg.Instances, inst)
+			}
+		case "ping-target":
+			cfg.PingTargets = nil
+			for _, value := range pingTargets {
+				target, err := monitor.ParsePingTarget(value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("-ping-target: %w", err))
+					continue
+				}
+				cfg.PingTargets = append(cfg.PingTargets, config.PingTarget{Name: target.Name, Host: target.Host})
+			}
+		}
+	})
+
+	if err := errors.Join(append(errs, cfg.Validate())...); err != nil {
+		return fmt.Errorf("invalid configuration\n  %s", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+
+	// The default CSV file is only written when no outputs are configured
+	if len(cfg.Writers) > 0 && !isFlagSet("csv") {
+		*csvFile = ""
+	}
+
+	obsHost := cfg.Address()
+	obsPassword := ""
+	obsInstances := cfg.MonitorInstances()
+	if len(obsInstances) == 0 {
+		resolver := password.NewResolver(*passwordFlag, cfg.Connection.PasswordFile, obsHost)
+		resolver.NoPrompt = *daemonMode
+		var err error
+		obsPassword, err = resolver.Resolve()
+		if err != nil {
+			return err
+		}
+	}
+	for i, inst := range cfg.Instances {
+		passwordFile := inst.PasswordFile
+		if passwordFile == "" {
+			passwordFile = cfg.Connection.PasswordFile
+		}
+
+		resolver := password.NewResolver(*passwordFlag, passwordFile, obsInstances[i].Host)
+		resolver.Instance = inst.Name
+		resolver.NoPrompt = *daemonMode
+		instancePassword, err := resolver.Resolve()
+		if err != nil {
+			return fmt.Errorf("instance %s: %w", inst.Name, err)
+		}
+		obsInstances[i].Password = instancePassword
+	}
+
+	aggregations, err := cfg.Aggregations()
+	if err != nil {
+		return err
+	}
+
+	alertRules, err := cfg.AlertRules()
+	if err != nil {
+		return err
+	}
+
+	// The daemon logs to stderr, so stdout only holds the metrics of the console output
+	var logger *slog.Logger
+	if *daemonMode {
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
 	}
 
 	monitor, err := monitor.NewMonitor(monitor.ObsConnectionInfo{
-		Host:           fmt.Sprintf("%s:%s", *host, *port),
-		Password:       *password,
-		CSVFile:        *csvFile,
-		MetricInterval: *metricIntervalMs,
-		WriterInterval: *writerIntervalMs,
+		Host:             obsHost,
+		Password:         obsPassword,
+		CSVFile:          *csvFile,
+		Outputs:          cfg.Writers,
+		PrometheusListen: *prometheusListen,
+		MetricInterval:   cfg.Intervals.MetricMs,
+		WriterInterval:   cfg.Intervals.WriterMs,
+		Aggregations:     aggregations,
+		Probe:            cfg.Probe,
+		PingTargets:      cfg.MonitorPingTargets(),
+		Instances:        obsInstances,
+		Wait:             cfg.Connection.Wait || *daemonMode,
+		Logger:           logger,
+		SkipIdle:         cfg.SkipIdle,
+		Audio:            cfg.MonitorAudio(),
+		Alerts:           alertRules,
+		Webhooks:         cfg.WebhookEndpoints(),
 	})
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer monitor.Close()
 
-	fmt.Println("\nPress Ctrl-C to exit")
+	if *daemonMode {
+		notifySystemd(logger, "STATUS=Connecting to OBS")
+		go runWatchdog(logger, monitor.LastWrite, monitor.Done())
+	} else {
+		fmt.Println("\nPress Ctrl-C to exit")
+	}
+
+	// Signals are handled while starting, as connecting to OBS can take a while
+	go handleSignals(monitor, logger)
 
-	err = monitor.Start()
-	if err != nil {
-		fmt.Printf("Monitor error: %v\n", err)
-		os.Exit(1)
+	if err := monitor.Start(); err != nil {
+		if errors.Is(err, context.Canceled) {
+			<-monitor.Done()
+			return nil
+		}
+		if logger != nil {
+			logger.Error("Failed to start monitor", "error", err)
+			return errReported
+		}
+		return fmt.Errorf("failed to start monitor: %w", err)
+	}
+
+	if *daemonMode {
+		notifySystemd(logger, daemon.SdNotifyReady+"\nSTATUS=Monitoring OBS")
+	}
+	<-monitor.Done()
+	if *daemonMode {
+		notifySystemd(logger, daemon.SdNotifyStopping)
+	}
+	return nil
+}
+
+func aggregationNames() []string {
+	names := []string{}
+	for _, a := range metric.Aggregations {
+		names = append(names, string(a))
 	}
+	return names
+}
 
-	waitForExit(monitor)
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
-func waitForExit(mon *monitor.Monitor) {
+// handleSignals shuts the monitor down on SIGINT or SIGTERM
+func handleSignals(mon *monitor.Monitor, logger *slog.Logger) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
 	select {
-	case <-sigChan:
-		fmt.Println("\nReceived interrupt signal, shutting down...")
+	case sig := <-sigChan:
+		if logger != nil {
+			logger.Info("Received signal, shutting down", "signal", sig.String())
+		} else {
+			fmt.Println("\nReceived interrupt signal, shutting down...")
+		}
 		mon.Shutdown()
-		<-mon.Done()
 	case <-mon.Done():
 	}
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+)
+
+// notifySystemd sends a state to systemd, it does nothing when not started as a Type=notify service
+func notifySystemd(logger *slog.Logger, state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		logger.Warn("Failed to notify systemd", "state", state, "error", err)
+	}
+}
+
+// runWatchdog keeps the systemd watchdog satisfied while the monitor writes its rows, until done is closed.
+// It does nothing when WatchdogSec is not set for the service.
+func runWatchdog(logger *slog.Logger, lastWrite func() time.Time, done <-chan struct{}) {
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		logger.Warn("Failed to read systemd watchdog interval", "error", err)
+		return
+	}
+	if interval == 0 {
+		return
+	}
+
+	// Check twice per interval, so a single late notification does not trigger the watchdog
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// A monitor that stopped writing rows is stuck, so systemd restarts it
+			if last := lastWrite(); !last.IsZero() && time.Since(last) > interval {
+				since := time.Since(last)
+				logger.Warn(fmt.Sprintf("No metrics written for %v, not notifying the watchdog", since.Round(time.Second)))
+				continue
+			}
+			notifySystemd(logger, daemon.SdNotifyWatchdog)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operators lists the comparison operators of rules
+var Operators = []string{">", ">=", "<", "<=", "==", "!="}
+
+// Severities lists the severities of rules, from least to most severe
+var Severities = []string{"info", "warning", "critical"}
+
+// DefaultSeverity is the severity of rules without one
+const DefaultSeverity = "warning"
+
+// Rule fires when a column crosses its threshold for a while, e.g. obs_rtt_ms > 150 for 3 intervals
+type Rule struct {
+	Name      string
+	Metric    string
+	Operator  string
+	Threshold float64
+	// Resolve is the threshold at which a firing rule resolves again, the threshold when nil
+	Resolve *float64
+	// ForIntervals and ForDuration are how long the condition must hold before the rule fires, both 0 fires immediately
+	ForIntervals int
+	ForDuration  time.Duration
+	Severity     string
+}
+
+// Validate checks the name, metric, operator, severity and resolve threshold of the rule
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("alert rule without a name")
+	}
+
+	var errs []error
+	if r.Metric == "" {
+		errs = append(errs, fmt.Errorf("alert rule %s has no metric", r.Name))
+	}
+	if !slices.Contains(Operators, r.Operator) {
+		errs = append(errs, fmt.Errorf("alert rule %s has an unknown operator %q, expected one of %s", r.Name, r.Operator, strings.Join(Operators, " ")))
+	} else if r.Resolve != nil {
+		if err := ValidateResolve(r.Operator, r.Threshold, *r.Resolve); err != nil {
+			errs = append(errs, fmt.Errorf("alert rule %s: %w", r.Name, err))
+		}
+	}
+	if r.Severity != "" && !slices.Contains(Severities, r.Severity) {
+		errs = append(errs, fmt.Errorf("alert rule %s has an unknown severity %q, expected one of %s", r.Name, r.Severity, strings.Join(Severities, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateResolve checks that a resolve threshold is on the side of the threshold where the condition no longer holds,
+// e.g. at most the threshold for >. Otherwise a firing rule would resolve right away or never.
+func ValidateResolve(operator string, threshold, resolve float64) error {
+	switch operator {
+	case ">", ">=":
+		if resolve > threshold {
+			return fmt.Errorf("resolve %v must not be above the threshold %v for operator %s", resolve, threshold, operator)
+		}
+	case "<", "<=":
+		if resolve < threshold {
+			return fmt.Errorf("resolve %v must not be below the threshold %v for operator %s", resolve, threshold, operator)
+		}
+	default:
+		if resolve != threshold {
+			return fmt.Errorf("resolve is not supported for operator %s", operator)
+		}
+	}
+	return nil
+}
+
+// State is the state of a rule that is reported to the notifiers
+type State string
+
+const (
+	Firing   State = "firing"
+	Resolved State = "resolved"
+)
+
+// Alert reports that a rule started firing or resolved for an OBS instance
+type Alert struct {
+	Timestamp time.Time
+	// Instance is the name of the OBS instance, empty when a single instance is monitored
+	Instance string
+	Rule     string
+	Metric   string
+	Operator string
+	// Threshold is the threshold of the rule, or its resolve threshold for a resolved alert
+	Threshold float64
+	// Value is the value of the metric in the row that changed the state
+	Value    float64
+	Severity string
+	State    State
+}
+
+// Message describes the alert, e.g. "high-rtt firing: obs_rtt_ms 154.2 > 150"
+func (a Alert) Message() string {
+	value := strconv.FormatFloat(a.Value, 'f', -1, 64)
+	threshold := strconv.FormatFloat(a.Threshold, 'f', -1, 64)
+	if a.State == Resolved {
+		return fmt.Sprintf("%s resolved: %s %s", a.Rule, a.Metric, value)
+	}
+	return fmt.Sprintf("%s firing: %s %s %s %s", a.Rule, a.Metric, value, a.Operator, threshold)
+}
+
+// Notifier receives the alerts when a rule starts firing and when it resolves
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// Change is a change of an OBS instance that notifiers report next to the alerts
+type Change string
+
+const (
+	StreamStarted Change = "stream_started"
+	StreamStopped Change = "stream_stopped"
+	Disconnected  Change = "disconnected"
+	Reconnected   Change = "reconnected"
+)
+
+// StateChange reports a change of an OBS instance, e.g. a stream that started
+type StateChange struct {
+	Timestamp time.Time
+	// Instance is the name of the OBS instance, empty when a single instance is monitored
+	Instance string
+	Change   Change
+	// Message describes the change, e.g. "OBS started streaming"
+	Message string
+}
+
+// StateNotifier is implemented by notifiers that also report the state changes of the OBS instances
+type StateNotifier interface {
+	StateChanged(change StateChange) error
+}
+
+// LogNotifier logs the alerts, firing alerts as warnings
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(alert Alert) error {
+	attrs := []any{"rule", alert.Rule, "metric", alert.Metric, "value", alert.Value, "severity", alert.Severity, "state", string(alert.State)}
+	if alert.Instance != "" {
+		attrs = append(attrs, "instance", alert.Instance)
+	}
+
+	message := "Alert " + alert.Message()
+	if alert.Instance != "" {
+		message = fmt.Sprintf("Alert of %s %s", alert.Instance, alert.Message())
+	}
+	if alert.State == Firing {
+		n.logger.Warn(message, attrs...)
+	} else {
+		n.logger.Info(message, attrs...)
+	}
+	return nil
+}
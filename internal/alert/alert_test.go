@@ -0,0 +1,27 @@
+package alert
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	n := NewLogNotifier(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	n.Notify(Alert{Instance: "backup", Rule: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 150, Value: 200, Severity: "critical", State: Firing})
+	n.Notify(Alert{Rule: "high-rtt", Metric: "obs_rtt_ms", Value: 20, State: Resolved})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "level=WARN") || !strings.Contains(lines[0], `msg="Alert of backup high-rtt firing: obs_rtt_ms 200 > 150"`) {
+		t.Errorf("Expected a warning for the firing alert, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "level=INFO") || !strings.Contains(lines[1], `msg="Alert high-rtt resolved: obs_rtt_ms 20"`) {
+		t.Errorf("Expected an info message for the resolved alert, got %s", lines[1])
+	}
+}
@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"errors"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
+)
+
+// Engine evaluates the rules against every metrics row. A rule fires once when its condition held for long enough
+// and resolves once when its value crosses the resolve threshold, instead of reporting every row.
+type Engine struct {
+	rules []Rule
+	// interval is the writer interval, every row covers the interval before its timestamp
+	interval time.Duration
+	// states holds the state of every rule per OBS instance
+	states map[stateKey]*ruleState
+}
+
+type stateKey struct {
+	rule     int
+	instance string
+}
+
+type ruleState struct {
+	// since is the timestamp of the first row of the condition, rows counts the rows since then
+	since  time.Time
+	rows   int
+	firing bool
+}
+
+// NewEngine creates an engine for rules that are evaluated every writer interval
+func NewEngine(rules []Rule, interval time.Duration) (*Engine, error) {
+	var errs []error
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		interval: interval,
+		states:   map[stateKey]*ruleState{},
+	}
+	for _, rule := range rules {
+		if rule.Severity == "" {
+			rule.Severity = DefaultSeverity
+		}
+		e.rules = append(e.rules, rule)
+	}
+	return e, nil
+}
+
+// Rules returns the rules of the engine
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Evaluate updates the rules with a metrics row and returns the alerts of the rules that changed their state.
+// A row without a valid value of the metric leaves the state of the rule unchanged.
+func (e *Engine) Evaluate(data writer.MetricsData) []Alert {
+	var alerts []Alert
+	for i, rule := range e.rules {
+		s, ok := data.Sample(rule.Metric)
+		if !ok || !s.Valid {
+			continue
+		}
+
+		key := stateKey{rule: i, instance: data.Instance}
+		state, ok := e.states[key]
+		if !ok {
+			state = &ruleState{}
+			e.states[key] = state
+		}
+
+		alert := Alert{
+			Timestamp: data.Timestamp,
+			Instance:  data.Instance,
+			Rule:      rule.Name,
+			Metric:    rule.Metric,
+			Operator:  rule.Operator,
+			Threshold: rule.Threshold,
+			Value:     s.Value,
+			Severity:  rule.Severity,
+		}
+
+		if state.firing {
+			resolve := rule.Threshold
+			if rule.Resolve != nil {
+				resolve = *rule.Resolve
+			}
+			if !compare(rule.Operator, s.Value, resolve) {
+				*state = ruleState{}
+				alert.Threshold = resolve
+				alert.State = Resolved
+				alerts = append(alerts, alert)
+			}
+			continue
+		}
+
+		if !compare(rule.Operator, s.Value, rule.Threshold) {
+			*state = ruleState{}
+			continue
+		}
+		if state.rows == 0 {
+			state.since = data.Timestamp
+		}
+		state.rows++
+		if state.rows >= rule.ForIntervals && data.Timestamp.Sub(state.since)+e.interval >= rule.ForDuration {
+			state.firing = true
+			alert.State = Firing
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+func compare(operator string, value, threshold float64) bool {
+	switch operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
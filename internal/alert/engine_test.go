@@ -0,0 +1,152 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
+)
+
+var (
+	rttColumn    = metric.Descriptor{Name: "obs_rtt_ms", Precision: 2}
+	activeColumn = metric.Descriptor{Name: "stream_active", Kind: metric.Bool}
+)
+
+var started = time.Date(2025, 12, 23, 20, 0, 0, 0, time.UTC)
+
+func row(second int, samples ...metric.Sample) writer.MetricsData {
+	return writer.MetricsData{Timestamp: started.Add(time.Duration(second) * time.Second), Samples: samples}
+}
+
+func mustEngine(t *testing.T, rules ...Rule) *Engine {
+	t.Helper()
+	e, err := NewEngine(rules, time.Second)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	return e
+}
+
+// states evaluates rows of RTTs, one per second, and returns the states of the alerts per row
+func states(e *Engine, rtts ...float64) []State {
+	result := []State{}
+	for i, rtt := range rtts {
+		alerts := e.Evaluate(row(i, rttColumn.Sample(rtt)))
+		if len(alerts) == 0 {
+			result = append(result, "")
+			continue
+		}
+		result = append(result, alerts[0].State)
+	}
+	return result
+}
+
+func equalStates(t *testing.T, got []State, expected ...State) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestEngine_FiresOnceAndResolves(t *testing.T) {
+	e := mustEngine(t, Rule{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 150})
+
+	equalStates(t, states(e, 10, 200, 300, 250, 20, 30), "", Firing, "", "", Resolved, "")
+}
+
+func TestEngine_ForIntervals(t *testing.T) {
+	e := mustEngine(t, Rule{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 150, ForIntervals: 3})
+
+	// An interruption starts counting again
+	equalStates(t, states(e, 200, 200, 10, 200, 200, 200, 200), "", "", "", "", "", Firing, "")
+}
+
+func TestEngine_ForDuration(t *testing.T) {
+	e := mustEngine(t, Rule{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">=", Threshold: 150, ForDuration: 3 * time.Second})
+
+	// Every row covers the second before it, so the third row completes 3s
+	equalStates(t, states(e, 150, 150, 150), "", "", Firing)
+}
+
+func TestEngine_Hysteresis(t *testing.T) {
+	resolve := 80.0
+	e := mustEngine(t, Rule{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 100, Resolve: &resolve})
+
+	equalStates(t, states(e, 120, 90, 110, 80, 90), Firing, "", "", Resolved, "")
+}
+
+func TestEngine_Alert(t *testing.T) {
+	resolve := 80.0
+	e := mustEngine(t, Rule{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 100, Resolve: &resolve})
+
+	firing := e.Evaluate(row(0, rttColumn.Sample(154.2)))[0]
+	if firing.Severity != DefaultSeverity || firing.Value != 154.2 || firing.Threshold != 100 || !firing.Timestamp.Equal(started) {
+		t.Errorf("Unexpected firing alert %+v", firing)
+	}
+	if firing.Message() != "high-rtt firing: obs_rtt_ms 154.2 > 100" {
+		t.Errorf("Unexpected message %q", firing.Message())
+	}
+
+	resolved := e.Evaluate(row(1, rttColumn.Sample(42)))[0]
+	if resolved.Threshold != 80 || resolved.Message() != "high-rtt resolved: obs_rtt_ms 42" {
+		t.Errorf("Unexpected resolved alert %+v: %s", resolved, resolved.Message())
+	}
+}
+
+func TestEngine_MissingValueKeepsState(t *testing.T) {
+	e := mustEngine(t, Rule{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 150, ForIntervals: 2})
+
+	e.Evaluate(row(0, rttColumn.Sample(200)))
+	if alerts := e.Evaluate(row(1, metric.Sample{Descriptor: rttColumn})); len(alerts) != 0 {
+		t.Errorf("Expected no alert without a value, got %v", alerts)
+	}
+	if alerts := e.Evaluate(row(2, rttColumn.Sample(200))); len(alerts) != 1 {
+		t.Errorf("Expected the rule to fire on the second value, got %v", alerts)
+	}
+}
+
+func TestEngine_BoolColumn(t *testing.T) {
+	e := mustEngine(t, Rule{Name: "offline", Metric: "stream_active", Operator: "==", Threshold: 0, Severity: "critical"})
+
+	alerts := e.Evaluate(row(0, activeColumn.BoolSample(false)))
+	if len(alerts) != 1 || alerts[0].Severity != "critical" {
+		t.Errorf("Expected a critical alert for the stopped stream, got %v", alerts)
+	}
+}
+
+func TestEngine_Instances(t *testing.T) {
+	e := mustEngine(t, Rule{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 150})
+
+	main := row(0, rttColumn.Sample(200))
+	main.Instance = "main"
+	backup := row(0, rttColumn.Sample(200))
+	backup.Instance = "backup"
+
+	if len(e.Evaluate(main)) != 1 || len(e.Evaluate(backup)) != 1 {
+		t.Error("Expected the rule to fire for every instance")
+	}
+	if alerts := e.Evaluate(main); len(alerts) != 0 {
+		t.Errorf("Expected the rule to keep firing for main, got %v", alerts)
+	}
+}
+
+func TestNewEngine_InvalidRules(t *testing.T) {
+	_, err := NewEngine([]Rule{{Name: "rtt", Metric: "obs_rtt_ms", Operator: "=>"}, {Name: "cpu"}}, time.Second)
+	if err == nil {
+		t.Fatal("Expected an error for invalid rules")
+	}
+}
+
+func TestNewEngine_ResolveOnWrongSide(t *testing.T) {
+	resolve := 200.0
+	_, err := NewEngine([]Rule{{Name: "high-rtt", Metric: "obs_rtt_ms", Operator: ">", Threshold: 150, Resolve: &resolve}}, time.Second)
+	if err == nil {
+		t.Fatal("Expected an error for a resolve threshold above the threshold of >")
+	}
+}
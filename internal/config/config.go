@@ -0,0 +1,428 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+	"github.com/joepadmiraal/obs-monitor/internal/monitor"
+	"github.com/joepadmiraal/obs-monitor/internal/webhook"
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
+	"go.yaml.in/yaml/v3"
+)
+
+// Config holds all settings of the monitor, it can be loaded from a YAML or TOML file
+type Config struct {
+	Connection Connection `yaml:"connection" toml:"connection"`
+	// Instances are monitored instead of the single instance of Connection when given
+	Instances   []Instance   `yaml:"instances" toml:"instances"`
+	Intervals   Intervals    `yaml:"intervals" toml:"intervals"`
+	Probe       string       `yaml:"probe" toml:"probe"`
+	Aggregate   []string     `yaml:"aggregate" toml:"aggregate"`
+	PingTargets []PingTarget `yaml:"ping_targets" toml:"ping_targets"`
+	// Writers are output specs in the form kind[:target], like the -output flag
+	Writers []string `yaml:"writers" toml:"writers"`
+	// SkipIdle drops the rows written while the stream is not live, like the -skip-idle flag
+	SkipIdle bool        `yaml:"skip_idle" toml:"skip_idle"`
+	Audio    Audio       `yaml:"audio" toml:"audio"`
+	Alerts   []AlertRule `yaml:"alerts" toml:"alerts"`
+	Webhooks []Webhook   `yaml:"webhooks" toml:"webhooks"`
+}
+
+// Connection has no password, it is read from a password file, the environment or the OS keyring instead
+type Connection struct {
+	Host         string `yaml:"host" toml:"host"`
+	Port         int    `yaml:"port" toml:"port"`
+	PasswordFile string `yaml:"password_file" toml:"password_file"`
+	// Wait keeps retrying to connect until OBS is started, like the -wait flag
+	Wait bool `yaml:"wait" toml:"wait"`
+}
+
+// Instance is a named OBS instance, the port defaults to the port of the connection
+type Instance struct {
+	Name         string `yaml:"name" toml:"name"`
+	Host         string `yaml:"host" toml:"host"`
+	Port         int    `yaml:"port" toml:"port"`
+	PasswordFile string `yaml:"password_file" toml:"password_file"`
+}
+
+// ParseInstance parses an instance in the form name=host[:port]
+func ParseInstance(value string) (Instance, error) {
+	name, address, ok := strings.Cut(value, "=")
+	if !ok {
+		return Instance{}, fmt.Errorf("invalid instance %q, expected name=host[:port]", value)
+	}
+
+	inst := Instance{Name: strings.TrimSpace(name), Host: strings.TrimSpace(address)}
+	if host, port, err := net.SplitHostPort(inst.Host); err == nil {
+		portNumber, err := strconv.Atoi(port)
+		if err != nil {
+			return Instance{}, fmt.Errorf("invalid port %q of instance %s", port, inst.Name)
+		}
+		inst.Host = host
+		inst.Port = portNumber
+	}
+	return inst, nil
+}
+
+type Intervals struct {
+	MetricMs int `yaml:"metric_ms" toml:"metric_ms"`
+	WriterMs int `yaml:"writer_ms" toml:"writer_ms"`
+}
+
+type PingTarget struct {
+	Name string `yaml:"name" toml:"name"`
+	Host string `yaml:"host" toml:"host"`
+}
+
+// Audio selects the inputs whose audio levels are monitored, like the -audio-input flag
+type Audio struct {
+	Inputs []string `yaml:"inputs" toml:"inputs"`
+	// SilenceThresholdDB is the peak level in dBFS below which an input is quiet
+	SilenceThresholdDB float64 `yaml:"silence_threshold_db" toml:"silence_threshold_db"`
+	// SilenceSeconds is how long an input must be quiet before it is flagged as silent
+	SilenceSeconds int `yaml:"silence_seconds" toml:"silence_seconds"`
+}
+
+// AlertRule fires when a column crosses its threshold for a while
+type AlertRule struct {
+	Name      string    `yaml:"name" toml:"name"`
+	Metric    string    `yaml:"metric" toml:"metric"`
+	Operator  string    `yaml:"operator" toml:"operator"`
+	Threshold Threshold `yaml:"threshold" toml:"threshold"`
+	// For is how long the condition must hold before the rule fires, empty fires immediately
+	For      Period `yaml:"for" toml:"for"`
+	Severity string `yaml:"severity" toml:"severity"`
+	// Resolve is the threshold at which a firing rule resolves again, the threshold when not set
+	Resolve *float64 `yaml:"resolve" toml:"resolve"`
+}
+
+// Webhook is an HTTP endpoint that receives the alerts and the stream and connection changes
+type Webhook struct {
+	URL string `yaml:"url" toml:"url"`
+	// Format is one of generic, slack, discord or teams, generic when empty
+	Format    string `yaml:"format" toml:"format"`
+	TimeoutMs int    `yaml:"timeout_ms" toml:"timeout_ms"`
+	// Retries is a pointer, so 0 disables the retries instead of selecting the default
+	Retries *int `yaml:"retries" toml:"retries"`
+	// RateLimit is the maximum number of messages per minute
+	RateLimit int `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+// Period is either a number of writer intervals like 3 or a duration like 10s
+type Period string
+
+// UnmarshalTOML accepts both an integer and a string, as YAML does
+func (p *Period) UnmarshalTOML(value any) error {
+	switch v := value.(type) {
+	case int64:
+		*p = Period(strconv.FormatInt(v, 10))
+	case string:
+		*p = Period(v)
+	default:
+		return fmt.Errorf("invalid period %v, expected a number of intervals or a duration like 10s", value)
+	}
+	return nil
+}
+
+// Parse returns the number of writer intervals or the duration, only one of them is set
+func (p Period) Parse() (intervals int, duration time.Duration, err error) {
+	if p == "" {
+		return 0, 0, nil
+	}
+
+	if intervals, err := strconv.Atoi(string(p)); err == nil {
+		if intervals < 0 {
+			return 0, 0, fmt.Errorf("must not be negative, got %d", intervals)
+		}
+		return intervals, 0, nil
+	}
+
+	duration, err = time.ParseDuration(string(p))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid value %q, expected a number of intervals or a duration like 10s", string(p))
+	}
+	if duration < 0 {
+		return 0, 0, fmt.Errorf("must not be negative, got %v", duration)
+	}
+	return 0, duration, nil
+}
+
+// Threshold is a number, or true or false for the boolean metrics that are 1 when true and 0 when false
+type Threshold float64
+
+// UnmarshalYAML accepts both a number and true or false
+func (t *Threshold) UnmarshalYAML(node *yaml.Node) error {
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	return t.set(value)
+}
+
+// UnmarshalTOML accepts both a number and true or false
+func (t *Threshold) UnmarshalTOML(value any) error {
+	return t.set(value)
+}
+
+func (t *Threshold) set(value any) error {
+	switch v := value.(type) {
+	case int:
+		*t = Threshold(v)
+	case int64:
+		*t = Threshold(v)
+	case float64:
+		*t = Threshold(v)
+	case bool:
+		*t = 0
+		if v {
+			*t = 1
+		}
+	default:
+		return fmt.Errorf("invalid threshold %v, expected a number, true or false", value)
+	}
+	return nil
+}
+
+// Default returns the configuration used for everything that is not set in a file or flag
+func Default() Config {
+	return Config{
+		Connection: Connection{
+			Host: "localhost",
+			Port: 4455,
+		},
+		Intervals: Intervals{
+			MetricMs: 1000,
+			WriterMs: 1000,
+		},
+		Probe: monitor.ProbeICMP,
+		Audio: Audio{
+			SilenceThresholdDB: -60,
+			SilenceSeconds:     5,
+		},
+	}
+}
+
+// Load reads a configuration file on top of the defaults, the format is chosen by the extension (.yaml, .yml or .toml).
+// Unknown keys are an error, so typos don't go unnoticed.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := Default()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(data), &cfg)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return Config{}, fmt.Errorf("failed to parse config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return Config{}, fmt.Errorf("unsupported config file extension %q, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the whole configuration and reports all problems at once
+func (c Config) Validate() error {
+	var errs []error
+	add := func(field string, err error) {
+		errs = append(errs, fmt.Errorf("%s: %w", field, err))
+	}
+
+	if c.Connection.Host == "" {
+		add("connection.host", errors.New("must not be empty"))
+	}
+	if c.Connection.Port < 1 || c.Connection.Port > 65535 {
+		add("connection.port", fmt.Errorf("%d is not a valid port", c.Connection.Port))
+	}
+
+	for i, inst := range c.Instances {
+		if inst.Port != 0 && (inst.Port < 1 || inst.Port > 65535) {
+			add(fmt.Sprintf("instances[%d].port", i), fmt.Errorf("%d is not a valid port", inst.Port))
+		}
+	}
+	if err := monitor.ValidateInstances(c.MonitorInstances()); err != nil {
+		add("instances", err)
+	}
+
+	if c.Intervals.MetricMs <= 0 {
+		add("intervals.metric_ms", fmt.Errorf("must be positive, got %d", c.Intervals.MetricMs))
+	}
+	if c.Intervals.WriterMs <= 0 {
+		add("intervals.writer_ms", fmt.Errorf("must be positive, got %d", c.Intervals.WriterMs))
+	}
+	if c.Intervals.MetricMs > c.Intervals.WriterMs {
+		add("intervals", fmt.Errorf("metric interval (%dms) cannot be higher than writer interval (%dms)", c.Intervals.MetricMs, c.Intervals.WriterMs))
+	}
+
+	if !slices.Contains(monitor.ProbeModes, c.Probe) {
+		add("probe", fmt.Errorf("unknown probe %q, expected one of %s", c.Probe, strings.Join(monitor.ProbeModes, ", ")))
+	}
+
+	if _, err := c.Aggregations(); err != nil {
+		add("aggregate", err)
+	}
+
+	if err := monitor.ValidatePingTargets(c.MonitorPingTargets()); err != nil {
+		add("ping_targets", err)
+	}
+
+	if err := monitor.ValidateAudio(c.MonitorAudio()); err != nil {
+		add("audio", err)
+	}
+
+	for i, spec := range c.Writers {
+		if err := writer.ValidateSpec(spec); err != nil {
+			add(fmt.Sprintf("writers[%d]", i), err)
+		}
+	}
+
+	names := map[string]bool{}
+	for i, rule := range c.Alerts {
+		field := fmt.Sprintf("alerts[%d]", i)
+		// The rule itself is validated by the alert package, only the name and the period are part of the configuration
+		if rule.Name != "" && names[rule.Name] {
+			add(field+".name", fmt.Errorf("duplicate alert name %s", rule.Name))
+		}
+		names[rule.Name] = true
+
+		intervals, duration, err := rule.For.Parse()
+		if err != nil {
+			add(field+".for", err)
+		}
+		if err := rule.alertRule(intervals, duration).Validate(); err != nil {
+			add(field, err)
+		}
+	}
+
+	for i, endpoint := range c.WebhookEndpoints() {
+		if err := endpoint.Validate(); err != nil {
+			add(fmt.Sprintf("webhooks[%d]", i), err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Aggregations returns the parsed aggregations
+func (c Config) Aggregations() ([]metric.Aggregation, error) {
+	return metric.ParseAggregations(strings.Join(c.Aggregate, ","))
+}
+
+// MonitorInstances returns the instances for the monitor, without their passwords
+func (c Config) MonitorInstances() []monitor.Instance {
+	instances := make([]monitor.Instance, 0, len(c.Instances))
+	for _, inst := range c.Instances {
+		instances = append(instances, monitor.Instance{Name: inst.Name, Host: c.InstanceAddress(inst)})
+	}
+	return instances
+}
+
+// InstanceAddress returns the host:port of an instance
+func (c Config) InstanceAddress(inst Instance) string {
+	if inst.Host == "" {
+		return ""
+	}
+	port := inst.Port
+	if port == 0 {
+		port = c.Connection.Port
+	}
+	return net.JoinHostPort(inst.Host, strconv.Itoa(port))
+}
+
+// Address returns the host:port of the single instance of the connection
+func (c Config) Address() string {
+	return net.JoinHostPort(c.Connection.Host, strconv.Itoa(c.Connection.Port))
+}
+
+// MonitorPingTargets returns the ping targets for the monitor
+func (c Config) MonitorPingTargets() []monitor.PingTarget {
+	targets := make([]monitor.PingTarget, 0, len(c.PingTargets))
+	for _, target := range c.PingTargets {
+		targets = append(targets, monitor.PingTarget{Name: target.Name, Host: target.Host})
+	}
+	return targets
+}
+
+// MonitorAudio returns the audio settings for the monitor
+func (c Config) MonitorAudio() monitor.Audio {
+	return monitor.Audio{
+		Inputs:             c.Audio.Inputs,
+		SilenceThresholdDB: c.Audio.SilenceThresholdDB,
+		SilenceDuration:    time.Duration(c.Audio.SilenceSeconds) * time.Second,
+	}
+}
+
+// AlertRules returns the alert rules for the monitor
+func (c Config) AlertRules() ([]alert.Rule, error) {
+	rules := make([]alert.Rule, 0, len(c.Alerts))
+	for _, rule := range c.Alerts {
+		intervals, duration, err := rule.For.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("alert %s: %w", rule.Name, err)
+		}
+		rules = append(rules, rule.alertRule(intervals, duration))
+	}
+	return rules, nil
+}
+
+func (r AlertRule) alertRule(intervals int, duration time.Duration) alert.Rule {
+	return alert.Rule{
+		Name:         r.Name,
+		Metric:       r.Metric,
+		Operator:     r.Operator,
+		Threshold:    float64(r.Threshold),
+		Resolve:      r.Resolve,
+		ForIntervals: intervals,
+		ForDuration:  duration,
+		Severity:     r.Severity,
+	}
+}
+
+// WebhookEndpoints returns the webhook endpoints for the monitor, with the defaults for the settings that are not set
+func (c Config) WebhookEndpoints() []webhook.Endpoint {
+	endpoints := make([]webhook.Endpoint, 0, len(c.Webhooks))
+	for _, w := range c.Webhooks {
+		endpoint := webhook.Endpoint{
+			URL:       w.URL,
+			Format:    w.Format,
+			Timeout:   webhook.DefaultTimeout,
+			Retries:   webhook.DefaultRetries,
+			RateLimit: webhook.DefaultRateLimit,
+		}
+		if w.TimeoutMs != 0 {
+			endpoint.Timeout = time.Duration(w.TimeoutMs) * time.Millisecond
+		}
+		if w.Retries != nil {
+			endpoint.Retries = *w.Retries
+		}
+		if w.RateLimit != 0 {
+			endpoint.RateLimit = w.RateLimit
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
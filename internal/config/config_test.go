@@ -0,0 +1,434 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/webhook"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+const yamlConfig = `
+connection:
+  host: obs.local
+  wait: true
+intervals:
+  writer_ms: 5000
+probe: both
+aggregate: [p95, avg]
+ping_targets:
+  - name: gateway
+    host: 192.168.1.1
+writers:
+  - console
+  - csv:rig.csv
+skip_idle: true
+audio:
+  inputs: [Mic/Aux, Desktop Audio]
+  silence_seconds: 10
+alerts:
+  - name: high-rtt
+    metric: obs_rtt_ms
+    operator: ">"
+    threshold: 100
+    for: 3
+    severity: critical
+    resolve: 80
+webhooks:
+  - url: https://hooks.slack.com/services/T0/B0/secret
+    format: slack
+    retries: 0
+  - url: http://localhost:9000/obs
+    timeout_ms: 2000
+    rate_limit: 30
+`
+
+const tomlConfig = `
+probe = "both"
+aggregate = ["p95", "avg"]
+writers = ["console", "csv:rig.csv"]
+skip_idle = true
+
+[connection]
+host = "obs.local"
+wait = true
+
+[intervals]
+writer_ms = 5000
+
+[[ping_targets]]
+name = "gateway"
+host = "192.168.1.1"
+
+[audio]
+inputs = ["Mic/Aux", "Desktop Audio"]
+silence_seconds = 10
+
+[[alerts]]
+name = "high-rtt"
+metric = "obs_rtt_ms"
+operator = ">"
+threshold = 100
+for = 3
+severity = "critical"
+resolve = 80
+
+[[webhooks]]
+url = "https://hooks.slack.com/services/T0/B0/secret"
+format = "slack"
+retries = 0
+
+[[webhooks]]
+url = "http://localhost:9000/obs"
+timeout_ms = 2000
+rate_limit = 30
+`
+
+func TestLoad_Formats(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "YAML", file: "obs-monitor.yaml", content: yamlConfig},
+		{name: "YML", file: "obs-monitor.yml", content: yamlConfig},
+		{name: "TOML", file: "obs-monitor.toml", content: tomlConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(writeFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+
+			if cfg.Connection.Host != "obs.local" {
+				t.Errorf("Expected host obs.local, got %s", cfg.Connection.Host)
+			}
+			if cfg.Connection.Port != 4455 {
+				t.Errorf("Expected default port 4455, got %d", cfg.Connection.Port)
+			}
+			if !cfg.Connection.Wait {
+				t.Error("Expected wait to be enabled")
+			}
+			if cfg.Intervals.MetricMs != 1000 || cfg.Intervals.WriterMs != 5000 {
+				t.Errorf("Expected intervals 1000/5000, got %+v", cfg.Intervals)
+			}
+			if cfg.Probe != "both" {
+				t.Errorf("Expected probe both, got %s", cfg.Probe)
+			}
+			if len(cfg.PingTargets) != 1 || cfg.PingTargets[0].Name != "gateway" {
+				t.Errorf("Expected gateway ping target, got %+v", cfg.PingTargets)
+			}
+			if len(cfg.Writers) != 2 || cfg.Writers[1] != "csv:rig.csv" {
+				t.Errorf("Expected console and csv writers, got %v", cfg.Writers)
+			}
+
+			if !cfg.SkipIdle {
+				t.Error("Expected idle rows to be skipped")
+			}
+
+			audio := cfg.MonitorAudio()
+			if len(audio.Inputs) != 2 || audio.Inputs[0] != "Mic/Aux" {
+				t.Errorf("Expected 2 audio inputs, got %v", audio.Inputs)
+			}
+			if audio.SilenceThresholdDB != -60 || audio.SilenceDuration != 10*time.Second {
+				t.Errorf("Expected the default threshold and 10s of silence, got %+v", audio)
+			}
+
+			if len(cfg.Alerts) != 1 {
+				t.Fatalf("Expected 1 alert rule, got %d", len(cfg.Alerts))
+			}
+			rule := cfg.Alerts[0]
+			if rule.For != "3" || rule.Threshold != 100 || rule.Severity != "critical" {
+				t.Errorf("Unexpected alert rule %+v", rule)
+			}
+			if rule.Resolve == nil || *rule.Resolve != 80 {
+				t.Errorf("Expected resolve threshold 80, got %v", rule.Resolve)
+			}
+
+			rules, err := cfg.AlertRules()
+			if err != nil {
+				t.Fatalf("AlertRules failed: %v", err)
+			}
+			if rules[0].ForIntervals != 3 || rules[0].ForDuration != 0 || *rules[0].Resolve != 80 {
+				t.Errorf("Unexpected rule %+v", rules[0])
+			}
+
+			endpoints := cfg.WebhookEndpoints()
+			if len(endpoints) != 2 {
+				t.Fatalf("Expected 2 webhooks, got %+v", endpoints)
+			}
+			if endpoints[0].Format != "slack" || endpoints[0].Retries != 0 || endpoints[0].Timeout != webhook.DefaultTimeout || endpoints[0].RateLimit != webhook.DefaultRateLimit {
+				t.Errorf("Expected the slack webhook without retries, got %+v", endpoints[0])
+			}
+			if endpoints[1].Timeout != 2*time.Second || endpoints[1].Retries != webhook.DefaultRetries || endpoints[1].RateLimit != 30 {
+				t.Errorf("Expected the generic webhook with the default retries, got %+v", endpoints[1])
+			}
+
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Expected valid config, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoad_UnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "YAML", file: "obs-monitor.yaml", content: "intervals:\n  metric: 500\n"},
+		{name: "TOML", file: "obs-monitor.toml", content: "[intervals]\nmetric = 500\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(writeFile(t, tt.file, tt.content)); err == nil {
+				t.Error("Expected error for unknown key")
+			}
+		})
+	}
+}
+
+func TestLoad_EmptyFileKeepsDefaults(t *testing.T) {
+	cfg, err := Load(writeFile(t, "obs-monitor.yaml", ""))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Connection != Default().Connection || cfg.Intervals != Default().Intervals {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoad_UnsupportedExtension(t *testing.T) {
+	if _, err := Load(writeFile(t, "obs-monitor.json", "{}")); err == nil {
+		t.Error("Expected error for unsupported extension")
+	}
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Connection.Port = 0
+	cfg.Intervals.MetricMs = 5000
+	cfg.Probe = "udp"
+	cfg.Aggregate = []string{"p42"}
+	cfg.PingTargets = []PingTarget{{Name: "dns", Host: "1.1.1.1"}, {Name: "dns", Host: "8.8.8.8"}}
+	cfg.Writers = []string{"influx:localhost"}
+	cfg.Audio.SilenceThresholdDB = 10
+	cfg.Alerts = []AlertRule{{Name: "rtt", Metric: "obs_rtt_ms", Operator: "=>", For: "soon", Severity: "fatal"}}
+	cfg.Webhooks = []Webhook{{URL: "hooks.slack.com/services/T0", Format: "irc"}}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+
+	for _, field := range []string{
+		"connection.port",
+		"intervals: metric interval (5000ms) cannot be higher than writer interval (1000ms)",
+		"probe",
+		"aggregate",
+		"ping_targets",
+		"writers[0]",
+		"audio: silence threshold",
+		"alerts[0]: alert rule rtt has an unknown operator",
+		"alerts[0].for",
+		"unknown severity \"fatal\"",
+		"webhooks[0]: invalid webhook URL",
+		"unknown webhook format",
+	} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected error for %s in:\n%v", field, err)
+		}
+	}
+}
+
+func TestValidate_AlertResolve(t *testing.T) {
+	tests := []struct {
+		operator string
+		resolve  float64
+		valid    bool
+	}{
+		{">", 100, true},
+		{">", 150, true},
+		{">", 200, false},
+		{"<=", 200, true},
+		{"<=", 100, false},
+		{"==", 150, true},
+		{"!=", 100, false},
+	}
+
+	for _, tt := range tests {
+		cfg := Default()
+		resolve := tt.resolve
+		cfg.Alerts = []AlertRule{{Name: "rtt", Metric: "obs_rtt_ms", Operator: tt.operator, Threshold: 150, Resolve: &resolve}}
+
+		err := cfg.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("Expected resolve %v to be valid for %s, got %v", tt.resolve, tt.operator, err)
+		}
+		if !tt.valid && (err == nil || !strings.Contains(err.Error(), "alerts[0]: alert rule rtt: resolve")) {
+			t.Errorf("Expected an error for resolve %v with %s, got %v", tt.resolve, tt.operator, err)
+		}
+	}
+}
+
+func TestValidate_Default(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Expected default config to be valid, got %v", err)
+	}
+}
+
+func TestPeriod_Parse(t *testing.T) {
+	tests := []struct {
+		period    Period
+		intervals int
+		duration  time.Duration
+		wantErr   bool
+	}{
+		{period: "", intervals: 0, duration: 0},
+		{period: "3", intervals: 3},
+		{period: "10s", duration: 10 * time.Second},
+		{period: "1m30s", duration: 90 * time.Second},
+		{period: "-1", wantErr: true},
+		{period: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.period), func(t *testing.T) {
+			intervals, duration, err := tt.period.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if intervals != tt.intervals || duration != tt.duration {
+				t.Errorf("Expected %d intervals and %v, got %d and %v", tt.intervals, tt.duration, intervals, duration)
+			}
+		})
+	}
+}
+
+func TestLoad_BoolThreshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    Threshold
+	}{
+		{name: "YAML false", file: "obs-monitor.yaml", content: "alerts:\n  - name: offline\n    metric: stream_active\n    operator: \"==\"\n    threshold: false\n", want: 0},
+		{name: "YAML true", file: "obs-monitor.yaml", content: "alerts:\n  - name: reconnecting\n    metric: output_reconnecting\n    operator: \"==\"\n    threshold: true\n", want: 1},
+		{name: "YAML float", file: "obs-monitor.yaml", content: "alerts:\n  - name: high-rtt\n    metric: obs_rtt_ms\n    operator: \">\"\n    threshold: 99.5\n", want: 99.5},
+		{name: "TOML false", file: "obs-monitor.toml", content: "[[alerts]]\nname = \"offline\"\nmetric = \"stream_active\"\noperator = \"==\"\nthreshold = false\n", want: 0},
+		{name: "TOML true", file: "obs-monitor.toml", content: "[[alerts]]\nname = \"reconnecting\"\nmetric = \"output_reconnecting\"\noperator = \"==\"\nthreshold = true\n", want: 1},
+		{name: "TOML float", file: "obs-monitor.toml", content: "[[alerts]]\nname = \"high-rtt\"\nmetric = \"obs_rtt_ms\"\noperator = \">\"\nthreshold = 99.5\n", want: 99.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(writeFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Alerts[0].Threshold != tt.want {
+				t.Errorf("Expected threshold %v, got %v", tt.want, cfg.Alerts[0].Threshold)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidThreshold(t *testing.T) {
+	if _, err := Load(writeFile(t, "obs-monitor.yaml", "alerts:\n  - name: rtt\n    threshold: high\n")); err == nil {
+		t.Error("Expected error for a threshold that is not a number or boolean")
+	}
+}
+
+func TestParseInstance(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Instance
+		wantErr bool
+	}{
+		{value: "main=obs-main.local", want: Instance{Name: "main", Host: "obs-main.local"}},
+		{value: "backup=10.0.0.2:4456", want: Instance{Name: "backup", Host: "10.0.0.2", Port: 4456}},
+		{value: "obs-main.local", wantErr: true},
+		{value: "main=host:port", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			inst, err := ParseInstance(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if inst != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, inst)
+			}
+		})
+	}
+}
+
+func TestLoad_Instances(t *testing.T) {
+	path := writeFile(t, "obs-monitor.yaml", `
+connection:
+  port: 4455
+instances:
+  - name: main
+    host: obs-main.local
+  - name: backup
+    host: obs-backup.local
+    port: 4456
+    password_file: /etc/obs-monitor/backup
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	instances := cfg.MonitorInstances()
+	if len(instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(instances))
+	}
+	if instances[0].Name != "main" || instances[0].Host != "obs-main.local:4455" {
+		t.Errorf("Expected main on the connection port, got %+v", instances[0])
+	}
+	if instances[1].Name != "backup" || instances[1].Host != "obs-backup.local:4456" {
+		t.Errorf("Expected backup on its own port, got %+v", instances[1])
+	}
+	if cfg.Instances[1].PasswordFile != "/etc/obs-monitor/backup" {
+		t.Errorf("Expected password file of backup, got %q", cfg.Instances[1].PasswordFile)
+	}
+}
+
+func TestValidate_Instances(t *testing.T) {
+	cfg := Default()
+	cfg.Instances = []Instance{
+		{Name: "main", Host: "obs-main.local"},
+		{Name: "main", Host: "obs-backup.local", Port: 70000},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"instances[1].port", "duplicate"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+}
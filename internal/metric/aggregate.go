@@ -0,0 +1,234 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Aggregation summarizes the values measured within a writer interval
+type Aggregation string
+
+const (
+	AggregateMin   Aggregation = "min"
+	AggregateAvg   Aggregation = "avg"
+	AggregateP50   Aggregation = "p50"
+	AggregateP95   Aggregation = "p95"
+	AggregateP99   Aggregation = "p99"
+	AggregateCount Aggregation = "count"
+)
+
+// Aggregations lists all aggregations in column order
+var Aggregations = []Aggregation{AggregateMin, AggregateAvg, AggregateP50, AggregateP95, AggregateP99, AggregateCount}
+
+// ParseAggregations parses a comma-separated list of aggregations, "all" selects every aggregation.
+// The result is in column order, independent of the order in the list.
+func ParseAggregations(list string) ([]Aggregation, error) {
+	selected := map[Aggregation]bool{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case name == "all":
+			for _, a := range Aggregations {
+				selected[a] = true
+			}
+		case slices.Contains(Aggregations, Aggregation(name)):
+			selected[Aggregation(name)] = true
+		default:
+			return nil, fmt.Errorf("unknown aggregation %q, expected one of %s or all", name, joinAggregations(Aggregations))
+		}
+	}
+
+	aggregations := []Aggregation{}
+	for _, a := range Aggregations {
+		if selected[a] {
+			aggregations = append(aggregations, a)
+		}
+	}
+	return aggregations, nil
+}
+
+func joinAggregations(aggregations []Aggregation) string {
+	names := make([]string, len(aggregations))
+	for i, a := range aggregations {
+		names[i] = string(a)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Window buffers the values measured within a writer interval, it is not safe for concurrent use
+type Window struct {
+	values []float64
+}
+
+func (w *Window) Add(value float64) {
+	w.values = append(w.values, value)
+}
+
+// Take returns the buffered values and starts a new window
+func (w *Window) Take() []float64 {
+	values := w.values
+	w.values = nil
+	return values
+}
+
+// column returns the descriptor of the column holding the aggregation of d
+func (a Aggregation) column(d Descriptor) Descriptor {
+	column := d
+	column.Name = d.Name + "_" + string(a)
+	column.Help = fmt.Sprintf("%s (%s within the writer interval)", strings.TrimSuffix(d.Help, "."), a)
+	if d.Family != "" {
+		column.Family = d.Family + "_" + string(a)
+	}
+	column.Kind = Gauge
+	if a == AggregateCount {
+		column.Unit = "samples"
+		column.Precision = 0
+	}
+	return column
+}
+
+// aggregatedColumns returns the columns of the aggregations of d
+func aggregatedColumns(d Descriptor, aggregations []Aggregation) []Descriptor {
+	columns := make([]Descriptor, 0, len(aggregations))
+	for _, a := range aggregations {
+		columns = append(columns, a.column(d))
+	}
+	return columns
+}
+
+// aggregatedSamples summarizes values into a sample per aggregation.
+// Without values only the count is valid.
+func aggregatedSamples(d Descriptor, aggregations []Aggregation, values []float64) []Sample {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	samples := make([]Sample, 0, len(aggregations))
+	for _, a := range aggregations {
+		column := a.column(d)
+		if a == AggregateCount {
+			samples = append(samples, column.Sample(float64(len(sorted))))
+			continue
+		}
+		if len(sorted) == 0 {
+			samples = append(samples, Sample{Descriptor: column})
+			continue
+		}
+
+		var value float64
+		switch a {
+		case AggregateMin:
+			value = sorted[0]
+		case AggregateAvg:
+			for _, v := range sorted {
+				value += v
+			}
+			value /= float64(len(sorted))
+		case AggregateP50:
+			value = percentile(sorted, 50)
+		case AggregateP95:
+			value = percentile(sorted, 95)
+		case AggregateP99:
+			value = percentile(sorted, 99)
+		}
+		samples = append(samples, column.Sample(value))
+	}
+	return samples
+}
+
+// Stats summarizes a series of values, e.g. the RTTs of a whole monitoring session
+type Stats struct {
+	Min   float64
+	Avg   float64
+	P95   float64
+	Max   float64
+	Count int
+}
+
+const (
+	// histogramMin is the upper bound of the first histogram bucket, smaller values are counted in it
+	histogramMin = 0.01
+	// histogramGrowth is the relative width of a histogram bucket, and the error of the estimated percentiles
+	histogramGrowth = 0.02
+	// histogramBuckets covers values up to about 76000, larger values are counted in the last bucket
+	histogramBuckets = 800
+)
+
+// Histogram summarizes a series of values in constant memory, e.g. the RTTs of a whole monitoring session.
+// The minimum, average and maximum are exact, the percentiles are estimated from logarithmic buckets.
+type Histogram struct {
+	buckets []int
+	count   int
+	sum     float64
+	min     float64
+	max     float64
+}
+
+// Add counts a value
+func (h *Histogram) Add(v float64) {
+	if h.buckets == nil {
+		h.buckets = make([]int, histogramBuckets)
+	}
+	h.buckets[histogramBucket(v)]++
+
+	if h.count == 0 || v < h.min {
+		h.min = v
+	}
+	if h.count == 0 || v > h.max {
+		h.max = v
+	}
+	h.count++
+	h.sum += v
+}
+
+// Stats returns the statistics of the values, the zero Stats when there are none
+func (h *Histogram) Stats() Stats {
+	if h.count == 0 {
+		return Stats{}
+	}
+	return Stats{
+		Min:   h.min,
+		Avg:   h.sum / float64(h.count),
+		P95:   h.percentile(95),
+		Max:   h.max,
+		Count: h.count,
+	}
+}
+
+// percentile estimates the p-th percentile as the middle of the bucket that holds its nearest rank
+func (h *Histogram) percentile(p float64) float64 {
+	rank := max(int(math.Ceil(p/100*float64(h.count))), 1)
+
+	var seen int
+	for i, n := range h.buckets {
+		seen += n
+		if seen >= rank {
+			estimate := histogramMin * math.Pow(1+histogramGrowth, float64(i)-0.5)
+			return min(max(estimate, h.min), h.max)
+		}
+	}
+	return h.max
+}
+
+// histogramBucket returns the bucket of v, bucket i holds the values up to histogramMin * (1+histogramGrowth)^i
+func histogramBucket(v float64) int {
+	if v <= histogramMin {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(v/histogramMin) / math.Log1p(histogramGrowth)))
+	return min(i, histogramBuckets-1)
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating linearly between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,142 @@
+package metric
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseAggregations(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []Aggregation
+		wantErr bool
+	}{
+		{name: "empty", list: "", want: []Aggregation{}},
+		{name: "single", list: "p95", want: []Aggregation{AggregateP95}},
+		{name: "column order", list: "count, p99,min", want: []Aggregation{AggregateMin, AggregateP99, AggregateCount}},
+		{name: "duplicates", list: "avg,avg", want: []Aggregation{AggregateAvg}},
+		{name: "all", list: "all", want: Aggregations},
+		{name: "unknown", list: "min,median", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAggregations(tt.list)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.list)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAggregations failed: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWindow_Take_StartsNewWindow(t *testing.T) {
+	var w Window
+	w.Add(1)
+	w.Add(2)
+
+	if values := w.Take(); !slices.Equal(values, []float64{1, 2}) {
+		t.Errorf("Expected [1 2], got %v", values)
+	}
+	if values := w.Take(); len(values) != 0 {
+		t.Errorf("Expected empty window after Take, got %v", values)
+	}
+}
+
+func TestAggregatedSamples_Values(t *testing.T) {
+	column := Descriptor{Name: "obs_rtt_ms", Precision: 2, Family: "rtt_ms"}
+	values := []float64{}
+	for i := 100; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+
+	samples := aggregatedSamples(column, Aggregations, values)
+
+	want := map[string]float64{
+		"obs_rtt_ms_min":   1,
+		"obs_rtt_ms_avg":   50.5,
+		"obs_rtt_ms_p50":   50.5,
+		"obs_rtt_ms_p95":   95.05,
+		"obs_rtt_ms_p99":   99.01,
+		"obs_rtt_ms_count": 100,
+	}
+	if len(samples) != len(want) {
+		t.Fatalf("Expected %d samples, got %d", len(want), len(samples))
+	}
+	for _, s := range samples {
+		if !s.Valid {
+			t.Errorf("Expected %s to be valid", s.Name)
+		}
+		if math.Abs(s.Value-want[s.Name]) > 1e-9 {
+			t.Errorf("Expected %s to be %v, got %v", s.Name, want[s.Name], s.Value)
+		}
+	}
+	if samples[0].Family != "rtt_ms_min" {
+		t.Errorf("Expected aggregated family rtt_ms_min, got %s", samples[0].Family)
+	}
+}
+
+func TestAggregatedSamples_EmptyWindow(t *testing.T) {
+	column := Descriptor{Name: "system_cpu_percent", Precision: 2}
+
+	samples := aggregatedSamples(column, []Aggregation{AggregateP95, AggregateCount}, nil)
+
+	if samples[0].Valid {
+		t.Error("Expected p95 of an empty window to be invalid")
+	}
+	if !samples[1].Valid || samples[1].Value != 0 {
+		t.Errorf("Expected count of an empty window to be 0, got %+v", samples[1])
+	}
+	if samples[1].Format() != "0" {
+		t.Errorf("Expected count without decimals, got %s", samples[1].Format())
+	}
+}
+
+func TestPercentile_SingleValue(t *testing.T) {
+	if got := percentile([]float64{42}, 99); got != 42 {
+		t.Errorf("Expected 42, got %v", got)
+	}
+}
+
+func TestHistogram_Stats(t *testing.T) {
+	var h Histogram
+	values := []float64{}
+	for i := 1000; i >= 1; i-- {
+		h.Add(float64(i) / 10)
+		values = append(values, float64(i)/10)
+	}
+	slices.Sort(values)
+
+	stats := h.Stats()
+	if stats.Min != 0.1 || stats.Max != 100 || stats.Count != 1000 || math.Abs(stats.Avg-50.05) > 1e-9 {
+		t.Errorf("Expected exact min, max, count and avg, got %+v", stats)
+	}
+	if exact := percentile(values, 95); math.Abs(stats.P95-exact)/exact > histogramGrowth {
+		t.Errorf("Expected p95 within %v of %v, got %v", histogramGrowth, exact, stats.P95)
+	}
+
+	var empty Histogram
+	if stats := empty.Stats(); stats != (Stats{}) {
+		t.Errorf("Expected zero stats without values, got %+v", stats)
+	}
+}
+
+func TestHistogram_SingleValue(t *testing.T) {
+	var h Histogram
+	h.Add(42)
+
+	expected := Stats{Min: 42, Avg: 42, P95: 42, Max: 42, Count: 1}
+	if stats := h.Stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
@@ -0,0 +1,220 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/andreykaipov/goobs/api/typedefs"
+)
+
+// minDB is the level of digital silence in dBFS, as the logarithm of 0 is not a number
+const minDB = -100.0
+
+// AudioMetrics follows the audio levels of OBS inputs, which OBS reports in InputVolumeMeters events
+type AudioMetrics struct {
+	// inputs holds the state per watched input name, order holds the names in the configured order
+	inputs map[string]*audioInput
+	order  []string
+	// threshold is the peak level in dBFS below which an input is quiet, an input that is quiet for silence is silent
+	threshold float64
+	silence   time.Duration
+	mu        sync.Mutex
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+type audioInput struct {
+	columns audioColumns
+	// peak is the highest peak within the window, squares and count hold the magnitudes for the RMS
+	peak    float64
+	squares float64
+	count   int
+	// quietSince is when the input became quiet, zero when it is not quiet
+	quietSince time.Time
+	// silent is set when the input was silent within the window
+	silent bool
+}
+
+type audioColumns struct {
+	peak   Descriptor
+	rms    Descriptor
+	silent Descriptor
+}
+
+func newAudioColumns(name string) audioColumns {
+	prefix := "audio_" + columnName(name) + "_"
+	labels := map[string]string{"input": name}
+
+	return audioColumns{
+		peak: Descriptor{
+			Name: prefix + "peak_db", Help: "Highest peak level of the OBS input.", Unit: "dBFS", Kind: Gauge, Precision: 1,
+			Family: "obs_audio_peak_db", Labels: labels,
+		},
+		rms: Descriptor{
+			Name: prefix + "rms_db", Help: "RMS level of the OBS input.", Unit: "dBFS", Kind: Gauge, Precision: 1,
+			Family: "obs_audio_rms_db", Labels: labels,
+		},
+		silent: Descriptor{
+			Name: prefix + "silent", Help: "Whether the OBS input was silent.", Kind: Bool,
+			Family: "obs_audio_silent", Labels: labels,
+		},
+	}
+}
+
+// NewAudioMetrics creates the audio collector for the named inputs, an input is silent once its peak level
+// stayed below thresholdDB for the silence duration
+func NewAudioMetrics(inputs []string, thresholdDB float64, silence time.Duration) (*AudioMetrics, error) {
+	a := &AudioMetrics{
+		inputs:    map[string]*audioInput{},
+		threshold: thresholdDB,
+		silence:   silence,
+		done:      make(chan struct{}),
+	}
+
+	columns := map[string]string{}
+	for _, name := range inputs {
+		column := columnName(name)
+		if column == "" {
+			return nil, fmt.Errorf("invalid audio input name %q", name)
+		}
+		if other, ok := columns[column]; ok {
+			return nil, fmt.Errorf("audio inputs %q and %q have the same column name %s", other, name, column)
+		}
+		columns[column] = name
+
+		a.inputs[name] = &audioInput{columns: newAudioColumns(name)}
+		a.order = append(a.order, name)
+	}
+	return a, nil
+}
+
+func (a *AudioMetrics) Name() string {
+	return "audio"
+}
+
+func (a *AudioMetrics) Describe() []Descriptor {
+	columns := []Descriptor{}
+	for _, name := range a.order {
+		c := a.inputs[name].columns
+		columns = append(columns, c.peak, c.rms, c.silent)
+	}
+	return columns
+}
+
+// Collect returns the levels of every input within the window, the levels are missing when OBS reported none
+func (a *AudioMetrics) Collect() ([]Sample, error) {
+	if a.stopped() {
+		return nil, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	samples := []Sample{}
+	for _, name := range a.order {
+		input := a.inputs[name]
+		c := input.columns
+
+		if input.count > 0 {
+			samples = append(samples,
+				c.peak.Sample(dbfs(input.peak)),
+				c.rms.Sample(dbfs(math.Sqrt(input.squares/float64(input.count)))),
+			)
+		}
+		samples = append(samples, c.silent.BoolSample(input.silent || a.isSilent(input, now)))
+
+		input.peak = 0
+		input.squares = 0
+		input.count = 0
+		input.silent = false
+	}
+	return samples, nil
+}
+
+// VolumeMeters follows the InputVolumeMeters events of OBS, which hold the levels of all active inputs
+func (a *AudioMetrics) VolumeMeters(meters []*typedefs.InputVolumeMeter) {
+	a.updateLevels(meters, time.Now())
+}
+
+func (a *AudioMetrics) updateLevels(meters []*typedefs.InputVolumeMeter, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	reported := map[string]bool{}
+	for _, meter := range meters {
+		input, ok := a.inputs[meter.Name]
+		if !ok {
+			continue
+		}
+		reported[meter.Name] = true
+
+		// Every channel has its magnitude, its peak and the peak before the volume fader
+		peak := 0.0
+		for _, channel := range meter.Levels {
+			input.squares += channel[0] * channel[0]
+			input.count++
+			peak = max(peak, channel[1])
+		}
+		input.peak = max(input.peak, peak)
+		a.updateQuiet(input, dbfs(peak) < a.threshold, at)
+	}
+
+	// An input that is not active, e.g. not part of any scene, is not heard either
+	for name, input := range a.inputs {
+		if !reported[name] {
+			a.updateQuiet(input, true, at)
+		}
+	}
+}
+
+func (a *AudioMetrics) updateQuiet(input *audioInput, quiet bool, at time.Time) {
+	if !quiet {
+		input.quietSince = time.Time{}
+		return
+	}
+	if input.quietSince.IsZero() {
+		input.quietSince = at
+	}
+	if a.isSilent(input, at) {
+		input.silent = true
+	}
+}
+
+func (a *AudioMetrics) isSilent(input *audioInput, at time.Time) bool {
+	return !input.quietSince.IsZero() && at.Sub(input.quietSince) >= a.silence
+}
+
+// dbfs converts a level from a multiplier to dBFS
+func dbfs(mul float64) float64 {
+	if mul <= 0 {
+		return minDB
+	}
+	return max(20*math.Log10(mul), minDB)
+}
+
+// Start waits until the collector is stopped, the levels are pushed by the OBS events
+func (a *AudioMetrics) Start(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+	case <-a.done:
+	}
+	return nil
+}
+
+// Stop ends the collector, e.g. when the OBS connection is lost
+func (a *AudioMetrics) Stop() {
+	a.stopOnce.Do(func() { close(a.done) })
+}
+
+func (a *AudioMetrics) stopped() bool {
+	select {
+	case <-a.done:
+		return true
+	default:
+		return false
+	}
+}
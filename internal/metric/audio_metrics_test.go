@@ -0,0 +1,110 @@
+package metric
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/andreykaipov/goobs/api/typedefs"
+)
+
+func meter(name string, magnitude, peak float64) *typedefs.InputVolumeMeter {
+	return &typedefs.InputVolumeMeter{Name: name, Levels: [][3]float64{{magnitude, peak, peak}, {magnitude, peak, peak}}}
+}
+
+func TestAudioMetrics_Collect_Levels(t *testing.T) {
+	a, err := NewAudioMetrics([]string{"Mic/Aux"}, -60, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewAudioMetrics failed: %v", err)
+	}
+
+	now := time.Now()
+	a.updateLevels([]*typedefs.InputVolumeMeter{meter("Mic/Aux", 0.1, 0.5), meter("Desktop Audio", 1, 1)}, now)
+	a.updateLevels([]*typedefs.InputVolumeMeter{meter("Mic/Aux", 0.1, 0.25)}, now)
+
+	values := sampleValues(mustCollect(t, a))
+	if math.Abs(values["audio_mic_aux_peak_db"]-(-6.02)) > 0.01 {
+		t.Errorf("Expected a peak of -6 dBFS, got %v", values["audio_mic_aux_peak_db"])
+	}
+	if math.Abs(values["audio_mic_aux_rms_db"]-(-20)) > 0.01 {
+		t.Errorf("Expected an RMS of -20 dBFS, got %v", values["audio_mic_aux_rms_db"])
+	}
+	if values["audio_mic_aux_silent"] != 0 {
+		t.Errorf("Expected the input not to be silent, got %v", values)
+	}
+	if _, ok := values["audio_desktop_audio_peak_db"]; ok {
+		t.Errorf("Expected inputs that are not watched to be ignored, got %v", values)
+	}
+
+	// Without levels in the window only the silence is known
+	samples := mustCollect(t, a)
+	if len(samples) != 1 || samples[0].Name != "audio_mic_aux_silent" {
+		t.Errorf("Expected only the silent column, got %v", samples)
+	}
+}
+
+func TestAudioMetrics_Silence(t *testing.T) {
+	a, _ := NewAudioMetrics([]string{"Mic/Aux"}, -50, 5*time.Second)
+	now := time.Now()
+
+	// Quiet, but not for long enough
+	a.updateLevels([]*typedefs.InputVolumeMeter{meter("Mic/Aux", 0.001, 0.001)}, now.Add(-3*time.Second))
+	if values := sampleValues(mustCollect(t, a)); values["audio_mic_aux_silent"] != 0 {
+		t.Errorf("Expected no silence after 3s, got %v", values)
+	}
+
+	a.updateLevels([]*typedefs.InputVolumeMeter{meter("Mic/Aux", 0, 0)}, now.Add(3*time.Second))
+	values := sampleValues(mustCollect(t, a))
+	if values["audio_mic_aux_silent"] != 1 || values["audio_mic_aux_peak_db"] != minDB {
+		t.Errorf("Expected silence after 6s at %v dBFS, got %v", minDB, values)
+	}
+
+	// A single loud level ends the silence
+	a.updateLevels([]*typedefs.InputVolumeMeter{meter("Mic/Aux", 0.2, 0.8)}, now.Add(4*time.Second))
+	if values := sampleValues(mustCollect(t, a)); values["audio_mic_aux_silent"] != 0 {
+		t.Errorf("Expected the silence to end, got %v", values)
+	}
+}
+
+func TestAudioMetrics_Silence_InactiveInput(t *testing.T) {
+	a, _ := NewAudioMetrics([]string{"Mic/Aux"}, -50, time.Second)
+	now := time.Now()
+
+	a.updateLevels([]*typedefs.InputVolumeMeter{meter("Desktop Audio", 1, 1)}, now.Add(-2*time.Second))
+	a.updateLevels([]*typedefs.InputVolumeMeter{meter("Desktop Audio", 1, 1)}, now)
+
+	if values := sampleValues(mustCollect(t, a)); values["audio_mic_aux_silent"] != 1 {
+		t.Errorf("Expected an input that OBS does not report to be silent, got %v", values)
+	}
+}
+
+func TestNewAudioMetrics_ColumnNames(t *testing.T) {
+	a, err := NewAudioMetrics([]string{"Mic/Aux", "Desktop Audio"}, -60, time.Second)
+	if err != nil {
+		t.Fatalf("NewAudioMetrics failed: %v", err)
+	}
+
+	columns := a.Describe()
+	if len(columns) != 6 || columns[3].Name != "audio_desktop_audio_peak_db" {
+		t.Errorf("Expected 3 columns per input in order, got %v", columns)
+	}
+	if columns[0].Family != "obs_audio_peak_db" || columns[0].Labels["input"] != "Mic/Aux" {
+		t.Errorf("Expected the column to be labeled with the input, got %+v", columns[0])
+	}
+
+	if _, err := NewAudioMetrics([]string{"Mic/Aux", "mic aux"}, -60, time.Second); err == nil {
+		t.Error("Expected an error for inputs with the same column name")
+	}
+	if _, err := NewAudioMetrics([]string{"!!"}, -60, time.Second); err == nil {
+		t.Error("Expected an error for an input without a column name")
+	}
+}
+
+func TestAudioMetrics_Stopped(t *testing.T) {
+	a, _ := NewAudioMetrics([]string{"Mic/Aux"}, -60, time.Second)
+	a.Stop()
+
+	if samples := mustCollect(t, a); len(samples) != 0 {
+		t.Errorf("Expected no samples of a stopped collector, got %v", samples)
+	}
+}
@@ -0,0 +1,190 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Kind describes how the value of a sample is interpreted
+type Kind int
+
+const (
+	// Gauge is a value measured within the writer interval
+	Gauge Kind = iota
+	// Counter is the increase of a monotonic counter within the writer interval
+	Counter
+	// Bool is either 0 (false) or 1 (true)
+	Bool
+)
+
+// Descriptor declares a column produced by a collector
+type Descriptor struct {
+	Name string
+	Help string
+	Unit string
+	Kind Kind
+	// Precision is the number of decimals used when the value is rendered as text
+	Precision int
+	// Family groups columns that only differ in their labels into one metric, e.g. rtt_ms{target="obs"}
+	Family string
+	Labels map[string]string
+}
+
+// Sample is the value of a declared column for one writer interval.
+// Valid is false when nothing was measured.
+type Sample struct {
+	Descriptor
+	Value float64
+	Valid bool
+}
+
+func (d Descriptor) Sample(value float64) Sample {
+	return Sample{Descriptor: d, Value: value, Valid: true}
+}
+
+func (d Descriptor) BoolSample(value bool) Sample {
+	if value {
+		return d.Sample(1)
+	}
+	return d.Sample(0)
+}
+
+// Format renders the value as text, it's empty when the sample is not valid
+func (s Sample) Format() string {
+	if !s.Valid {
+		return ""
+	}
+	if s.Kind == Bool {
+		return strconv.FormatBool(s.Value != 0)
+	}
+	return strconv.FormatFloat(s.Value, 'f', s.Precision, 64)
+}
+
+// CollectorError is an error a collector reported within a writer interval
+type CollectorError struct {
+	Collector string
+	Err       error
+}
+
+func (e CollectorError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Collector, e.Err)
+}
+
+// Collector measures a metric source and produces samples for the columns it declares
+type Collector interface {
+	// Name identifies the collector, e.g. in errors
+	Name() string
+	// Describe returns the columns the collector produces, in order
+	Describe() []Descriptor
+	// Start runs the measurement loop until ctx is done
+	Start(ctx context.Context) error
+	// Collect returns the samples of the current window and starts a new one
+	Collect() ([]Sample, error)
+}
+
+// Placeholder declares the columns of a collector that can only be created later, e.g. once OBS is started,
+// so they are part of the outputs from the start. It produces no samples until it is replaced by the collector.
+type Placeholder struct {
+	name    string
+	columns []Descriptor
+}
+
+// NewPlaceholder creates a placeholder with the name and the columns of c, which is not started
+func NewPlaceholder(c Collector) *Placeholder {
+	return &Placeholder{name: c.Name(), columns: c.Describe()}
+}
+
+func (p *Placeholder) Name() string {
+	return p.name
+}
+
+func (p *Placeholder) Describe() []Descriptor {
+	return p.columns
+}
+
+func (p *Placeholder) Start(ctx context.Context) error {
+	return nil
+}
+
+func (p *Placeholder) Collect() ([]Sample, error) {
+	return nil, nil
+}
+
+// Registry holds the collectors that make up a metrics row
+type Registry struct {
+	collectors []Collector
+	mu         sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a collector, an existing collector with the same name is replaced in place
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.collectors {
+		if existing.Name() == c.Name() {
+			r.collectors[i] = c
+			return
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// Names returns the names of all collectors in registration order
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := []string{}
+	for _, c := range r.collectors {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+// Describe returns the columns of all collectors in registration order
+func (r *Registry) Describe() []Descriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	columns := []Descriptor{}
+	for _, c := range r.collectors {
+		columns = append(columns, c.Describe()...)
+	}
+	return columns
+}
+
+// Collect gathers the current window of every collector.
+// It returns a sample for every declared column, columns without a measurement are not valid.
+func (r *Registry) Collect() ([]Sample, []CollectorError) {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	samples := []Sample{}
+	var errs []CollectorError
+	for _, c := range collectors {
+		collected, err := c.Collect()
+		if err != nil {
+			errs = append(errs, CollectorError{Collector: c.Name(), Err: err})
+		}
+
+		for _, column := range c.Describe() {
+			sample := Sample{Descriptor: column}
+			for _, s := range collected {
+				if s.Name == column.Name {
+					sample = s
+					break
+				}
+			}
+			samples = append(samples, sample)
+		}
+	}
+	return samples, errs
+}
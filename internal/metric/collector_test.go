@@ -0,0 +1,140 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeCollector struct {
+	name    string
+	columns []Descriptor
+	samples []Sample
+	err     error
+}
+
+func (c *fakeCollector) Name() string                { return c.name }
+func (c *fakeCollector) Describe() []Descriptor      { return c.columns }
+func (c *fakeCollector) Start(context.Context) error { return nil }
+func (c *fakeCollector) Collect() ([]Sample, error)  { return c.samples, c.err }
+
+func TestSample_Format(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample Sample
+		want   string
+	}{
+		{name: "gauge", sample: Descriptor{Precision: 2}.Sample(12.345), want: "12.35"},
+		{name: "counter", sample: Descriptor{Kind: Counter}.Sample(1024), want: "1024"},
+		{name: "bool true", sample: Descriptor{Kind: Bool}.BoolSample(true), want: "true"},
+		{name: "bool false", sample: Descriptor{Kind: Bool}.BoolSample(false), want: "false"},
+		{name: "invalid", sample: Sample{Descriptor: Descriptor{Precision: 2}}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sample.Format(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegistry_Describe_RegistrationOrder(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&fakeCollector{name: "first", columns: []Descriptor{{Name: "a"}, {Name: "b"}}})
+	r.Register(&fakeCollector{name: "second", columns: []Descriptor{{Name: "c"}}})
+
+	columns := r.Describe()
+
+	if len(columns) != 3 || columns[0].Name != "a" || columns[1].Name != "b" || columns[2].Name != "c" {
+		t.Errorf("Unexpected columns %v", columns)
+	}
+	if names := r.Names(); len(names) != 2 || names[0] != "first" || names[1] != "second" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestRegistry_Register_ReplacesInPlace(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&fakeCollector{name: "first", columns: []Descriptor{{Name: "a"}}})
+	r.Register(&fakeCollector{name: "second", columns: []Descriptor{{Name: "b"}}})
+	r.Register(&fakeCollector{name: "first", columns: []Descriptor{{Name: "a"}}, samples: []Sample{Descriptor{Name: "a"}.Sample(1)}})
+
+	samples, _ := r.Collect()
+
+	if len(samples) != 2 || samples[0].Name != "a" || samples[1].Name != "b" {
+		t.Fatalf("Expected replaced collector to keep its position, got %v", samples)
+	}
+	if !samples[0].Valid {
+		t.Error("Expected sample of the replacing collector")
+	}
+}
+
+func TestPlaceholder_ReplacedByCollector(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewTCPProbePlaceholder("obs", nil))
+	r.Register(&fakeCollector{name: "other", columns: []Descriptor{{Name: "b"}}})
+
+	samples, _ := r.Collect()
+	if len(samples) != 3 || samples[0].Name != "obs_tcp_connect_ms" || samples[0].Valid || samples[1].Valid {
+		t.Fatalf("Expected the invalid columns of the TCP probe, got %v", samples)
+	}
+
+	probe, err := NewTCPProbe("obs", "live.twitch.tv:1935", time.Second, nil)
+	if err != nil {
+		t.Fatalf("NewTCPProbe failed: %v", err)
+	}
+	probe.record(20*time.Millisecond, nil)
+	r.Register(probe)
+
+	samples, _ = r.Collect()
+	if len(samples) != 3 || samples[0].Name != "obs_tcp_connect_ms" || samples[0].Value != 20 {
+		t.Errorf("Expected the probe to take the place of its placeholder, got %v", samples)
+	}
+}
+
+func TestRegistry_Collect_AlignsDeclaredColumns(t *testing.T) {
+	a := Descriptor{Name: "a"}
+	b := Descriptor{Name: "b"}
+	r := NewRegistry()
+	r.Register(&fakeCollector{
+		name:    "collector",
+		columns: []Descriptor{a, b},
+		samples: []Sample{b.Sample(2), Descriptor{Name: "undeclared"}.Sample(3)},
+	})
+
+	samples, errs := r.Collect()
+
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected one sample per declared column, got %v", samples)
+	}
+	if samples[0].Name != "a" || samples[0].Valid {
+		t.Errorf("Expected missing column a to be invalid, got %+v", samples[0])
+	}
+	if samples[1].Name != "b" || !samples[1].Valid || samples[1].Value != 2 {
+		t.Errorf("Expected column b with value 2, got %+v", samples[1])
+	}
+}
+
+func TestRegistry_Collect_ReportsErrors(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&fakeCollector{name: "healthy", columns: []Descriptor{{Name: "a"}}})
+	r.Register(&fakeCollector{name: "failing", columns: []Descriptor{{Name: "b"}}, err: errors.New("timeout")})
+
+	samples, errs := r.Collect()
+
+	if len(samples) != 2 {
+		t.Errorf("Expected samples of all columns, got %v", samples)
+	}
+	if len(errs) != 1 || errs[0].Collector != "failing" {
+		t.Fatalf("Expected one error of the failing collector, got %v", errs)
+	}
+	if errs[0].Error() != "failing: timeout" {
+		t.Errorf("Unexpected error text %q", errs[0].Error())
+	}
+}
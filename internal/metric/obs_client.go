@@ -0,0 +1,20 @@
+package metric
+
+import (
+	"sync"
+
+	"github.com/andreykaipov/goobs"
+)
+
+// ObsClient is the OBS connection shared by the collectors of an OBS instance.
+// Requests are sent while holding the lock, so only one collector waits for a response at a time.
+// goobs only handles a lost connection once no request is waiting for its response timeout anymore,
+// with a waiting request per collector that would take a response timeout per collector.
+type ObsClient struct {
+	*goobs.Client
+	sync.Mutex
+}
+
+func NewObsClient(client *goobs.Client) *ObsClient {
+	return &ObsClient{Client: client}
+}
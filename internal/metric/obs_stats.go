@@ -1,60 +1,195 @@
 package metric
 
 import (
+	"context"
 	"sync"
 	"time"
 
-	"github.com/andreykaipov/goobs"
+	"github.com/andreykaipov/goobs/api/requests/general"
+)
+
+var (
+	obsCpuUsageColumn    = Descriptor{Name: "obs_cpu_percent", Help: "CPU usage of the OBS process.", Unit: "percent", Kind: Gauge, Precision: 2}
+	obsMemoryUsageColumn = Descriptor{Name: "obs_memory_mb", Help: "Memory usage of the OBS process.", Unit: "MB", Kind: Gauge, Precision: 2}
+	obsActiveFpsColumn   = Descriptor{Name: "obs_active_fps", Help: "Lowest frame rate rendered by OBS.", Unit: "fps", Kind: Gauge, Precision: 2}
+	obsRenderTimeColumn  = Descriptor{Name: "obs_render_time_ms", Help: "Highest average time OBS took to render a frame.", Unit: "ms", Kind: Gauge, Precision: 2}
+	renderSkippedColumn  = Descriptor{Name: "render_skipped_frames", Help: "Frames skipped by the OBS renderer (render lag).", Unit: "frames", Kind: Counter}
+	renderFramesColumn   = Descriptor{Name: "render_frames", Help: "Frames rendered by OBS.", Unit: "frames", Kind: Counter}
+	encoderSkippedColumn = Descriptor{Name: "encoder_skipped_frames", Help: "Frames skipped by the OBS encoders (encoding lag).", Unit: "frames", Kind: Counter}
+	encoderFramesColumn  = Descriptor{Name: "encoder_frames", Help: "Frames delivered to the OBS encoders.", Unit: "frames", Kind: Counter}
+	obsDiskSpaceColumn   = Descriptor{Name: "obs_disk_space_mb", Help: "Available disk space of the OBS recording path.", Unit: "MB", Kind: Gauge}
 )
 
 type ObsStats struct {
-	client            *goobs.Client
+	client            *ObsClient
 	maxObsCpuUsage    float64
 	maxObsMemoryUsage float64
-	lastError         error
-	measurementCount  int
-	mu                sync.Mutex
-	interval          time.Duration
+	obsCpuUsages      Window
+	// minActiveFps, maxRenderTime and minDiskSpace are only valid when renderCount is not 0
+	minActiveFps         float64
+	maxRenderTime        float64
+	minDiskSpace         float64
+	renderCount          int
+	renderSkippedFrames  deltaCounter
+	renderFrames         deltaCounter
+	encoderSkippedFrames deltaCounter
+	encoderFrames        deltaCounter
+	lastError            error
+	measurementCount     int
+	mu                   sync.Mutex
+	interval             time.Duration
+	aggregations         []Aggregation
+	done                 chan struct{}
+	stopOnce             sync.Once
 }
 
 type ObsStatsData struct {
 	Timestamp      time.Time
 	ObsCpuUsage    float64
 	ObsMemoryUsage float64
-	Error          error
+	// ObsCpuUsages contains every CPU usage measured within the window
+	ObsCpuUsages []float64
+	// ActiveFps, RenderTime and DiskSpace are the lowest, highest and lowest values within the window,
+	// RenderStats is false when OBS reported none
+	ActiveFps   float64
+	RenderTime  float64
+	DiskSpace   float64
+	RenderStats bool
+	// The frame counts are the deltas since the previous window, 0 for the first window
+	RenderSkippedFrames  float64
+	RenderFrames         float64
+	EncoderSkippedFrames float64
+	EncoderFrames        float64
+	Error                error
+}
+
+// deltaCounter turns a count of OBS, like frames or bytes, into deltas per window
+type deltaCounter struct {
+	latest float64
+	prev   float64
+	// seen is set by the first value, started once a window has passed
+	seen    bool
+	started bool
+}
+
+func (c *deltaCounter) update(value float64) {
+	c.latest = value
+	c.seen = true
 }
 
-func NewObsStats(client *goobs.Client, interval time.Duration) (*ObsStats, error) {
+// delta returns the increase since the previous call, a decrease means the count was reset, e.g. by an output that stopped
+func (c *deltaCounter) delta() float64 {
+	if !c.seen {
+		return 0
+	}
+	if !c.started {
+		c.started = true
+		c.prev = c.latest
+		return 0
+	}
+
+	delta := c.latest - c.prev
+	if delta < 0 {
+		delta = c.latest
+	}
+	c.prev = c.latest
+	return delta
+}
+
+// NewObsStats creates the OBS stats collector, the aggregations of the CPU usage are added as extra columns
+func NewObsStats(client *ObsClient, interval time.Duration, aggregations []Aggregation) (*ObsStats, error) {
 	return &ObsStats{
-		client:   client,
-		interval: interval,
+		client:       client,
+		interval:     interval,
+		aggregations: aggregations,
+		done:         make(chan struct{}),
 	}, nil
 }
 
+func (s *ObsStats) Name() string {
+	return "obs_stats"
+}
+
+func (s *ObsStats) Describe() []Descriptor {
+	columns := []Descriptor{obsCpuUsageColumn}
+	columns = append(columns, aggregatedColumns(obsCpuUsageColumn, s.aggregations)...)
+	return append(columns, obsMemoryUsageColumn, obsActiveFpsColumn, obsRenderTimeColumn,
+		renderSkippedColumn, renderFramesColumn, encoderSkippedColumn, encoderFramesColumn, obsDiskSpaceColumn)
+}
+
+// Collect returns the max OBS CPU and memory usage, the render stats and the frame count deltas,
+// a stopped collector has no samples
+func (s *ObsStats) Collect() ([]Sample, error) {
+	if s.stopped() {
+		return nil, nil
+	}
+
+	data := s.GetAndResetMaxValues()
+	samples := []Sample{obsCpuUsageColumn.Sample(data.ObsCpuUsage)}
+	samples = append(samples, aggregatedSamples(obsCpuUsageColumn, s.aggregations, data.ObsCpuUsages)...)
+	samples = append(samples, obsMemoryUsageColumn.Sample(data.ObsMemoryUsage))
+
+	// Without render stats a frame rate of 0 would look like a frozen OBS
+	if data.RenderStats {
+		samples = append(samples, obsActiveFpsColumn.Sample(data.ActiveFps), obsRenderTimeColumn.Sample(data.RenderTime))
+	} else {
+		samples = append(samples, Sample{Descriptor: obsActiveFpsColumn}, Sample{Descriptor: obsRenderTimeColumn})
+	}
+	samples = append(samples,
+		renderSkippedColumn.Sample(data.RenderSkippedFrames),
+		renderFramesColumn.Sample(data.RenderFrames),
+		encoderSkippedColumn.Sample(data.EncoderSkippedFrames),
+		encoderFramesColumn.Sample(data.EncoderFrames),
+	)
+	if data.RenderStats {
+		samples = append(samples, obsDiskSpaceColumn.Sample(data.DiskSpace))
+	} else {
+		samples = append(samples, Sample{Descriptor: obsDiskSpaceColumn})
+	}
+	return samples, data.Error
+}
+
 func (s *ObsStats) GetAndResetMaxValues() ObsStatsData {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	maxCpu := s.maxObsCpuUsage
 	maxMemory := s.maxObsMemoryUsage
+	cpuUsages := s.obsCpuUsages.Take()
 	err := s.lastError
 
+	data := ObsStatsData{
+		Timestamp:            time.Now(),
+		ObsCpuUsage:          maxCpu,
+		ObsMemoryUsage:       maxMemory,
+		ObsCpuUsages:         cpuUsages,
+		ActiveFps:            s.minActiveFps,
+		RenderTime:           s.maxRenderTime,
+		DiskSpace:            s.minDiskSpace,
+		RenderStats:          s.renderCount > 0,
+		RenderSkippedFrames:  s.renderSkippedFrames.delta(),
+		RenderFrames:         s.renderFrames.delta(),
+		EncoderSkippedFrames: s.encoderSkippedFrames.delta(),
+		EncoderFrames:        s.encoderFrames.delta(),
+		Error:                err,
+	}
+
 	s.maxObsCpuUsage = 0
 	s.maxObsMemoryUsage = 0
+	s.minActiveFps = 0
+	s.maxRenderTime = 0
+	s.minDiskSpace = 0
+	s.renderCount = 0
 	s.lastError = nil
 
-	return ObsStatsData{
-		Timestamp:      time.Now(),
-		ObsCpuUsage:    maxCpu,
-		ObsMemoryUsage: maxMemory,
-		Error:          err,
-	}
+	return data
 }
 
 func (s *ObsStats) updateStats(cpuUsage, memoryUsage float64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.obsCpuUsages.Add(cpuUsage)
 	if cpuUsage > s.maxObsCpuUsage {
 		s.maxObsCpuUsage = cpuUsage
 	}
@@ -64,18 +199,49 @@ func (s *ObsStats) updateStats(cpuUsage, memoryUsage float64) {
 	s.measurementCount++
 }
 
+func (s *ObsStats) updateRenderStats(stats *general.GetStatsResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.renderCount == 0 || stats.ActiveFps < s.minActiveFps {
+		s.minActiveFps = stats.ActiveFps
+	}
+	if stats.AverageFrameRenderTime > s.maxRenderTime {
+		s.maxRenderTime = stats.AverageFrameRenderTime
+	}
+	if s.renderCount == 0 || stats.AvailableDiskSpace < s.minDiskSpace {
+		s.minDiskSpace = stats.AvailableDiskSpace
+	}
+	s.renderCount++
+
+	s.renderSkippedFrames.update(stats.RenderSkippedFrames)
+	s.renderFrames.update(stats.RenderTotalFrames)
+	s.encoderSkippedFrames.update(stats.OutputSkippedFrames)
+	s.encoderFrames.update(stats.OutputTotalFrames)
+}
+
 func (s *ObsStats) recordError(err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.lastError = err
 }
 
-func (s *ObsStats) Start() error {
+func (s *ObsStats) Start(ctx context.Context) error {
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		s.client.Lock()
 		stats, err := s.client.General.GetStats()
+		s.client.Unlock()
 
 		if err != nil {
 			s.recordError(err)
@@ -83,7 +249,20 @@ func (s *ObsStats) Start() error {
 		}
 
 		s.updateStats(stats.CpuUsage, stats.MemoryUsage)
+		s.updateRenderStats(stats)
 	}
+}
 
-	return nil
+// Stop ends the collection loop started by Start, e.g. when the OBS connection is lost
+func (s *ObsStats) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *ObsStats) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
 }
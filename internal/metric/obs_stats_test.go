@@ -2,9 +2,12 @@ package metric
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/andreykaipov/goobs/api/requests/general"
 )
 
 func TestObsStats_GetAndResetMaxValues_ReturnsCorrectMaxValues(t *testing.T) {
@@ -130,7 +133,7 @@ func TestObsStats_GetAndResetMaxValues_TracksMaximum(t *testing.T) {
 func TestObsStats_NewObsStats(t *testing.T) {
 	interval := 100 * time.Millisecond
 
-	obs, err := NewObsStats(nil, interval)
+	obs, err := NewObsStats(nil, interval, nil)
 
 	if err != nil {
 		t.Fatalf("NewObsStats returned error: %v", err)
@@ -297,3 +300,103 @@ func TestObsStats_RecordError(t *testing.T) {
 		t.Error("Expected error to be returned in GetAndResetMaxValues")
 	}
 }
+
+func TestObsStats_Collect_Aggregations(t *testing.T) {
+	obs := &ObsStats{
+		aggregations: []Aggregation{AggregateMin, AggregateCount},
+		done:         make(chan struct{}),
+	}
+	obs.updateStats(10, 100)
+	obs.updateStats(30, 120)
+	obs.updateStats(20, 110)
+
+	columns := obs.Describe()
+	samples, err := obs.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	names := []string{}
+	for _, c := range columns {
+		names = append(names, c.Name)
+	}
+	expected := []string{"obs_cpu_percent", "obs_cpu_percent_min", "obs_cpu_percent_count", "obs_memory_mb",
+		"obs_active_fps", "obs_render_time_ms", "render_skipped_frames", "render_frames",
+		"encoder_skipped_frames", "encoder_frames", "obs_disk_space_mb"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected columns %v, got %v", expected, names)
+	}
+
+	values := map[string]float64{}
+	for _, s := range samples {
+		values[s.Name] = s.Value
+	}
+	if values["obs_cpu_percent"] != 30 || values["obs_cpu_percent_min"] != 10 || values["obs_cpu_percent_count"] != 3 {
+		t.Errorf("Unexpected samples %v", values)
+	}
+
+	samples, _ = obs.Collect()
+	for _, s := range samples {
+		if s.Name == "obs_cpu_percent_count" && s.Value != 0 {
+			t.Errorf("Expected the window to be reset, got count %v", s.Value)
+		}
+	}
+}
+
+func TestObsStats_Collect_RenderStats(t *testing.T) {
+	obs := &ObsStats{done: make(chan struct{})}
+	obs.updateRenderStats(&general.GetStatsResponse{ActiveFps: 60, AverageFrameRenderTime: 4, AvailableDiskSpace: 2000,
+		RenderSkippedFrames: 5, RenderTotalFrames: 1000, OutputSkippedFrames: 2, OutputTotalFrames: 900})
+
+	values := sampleValues(mustCollect(t, obs))
+	if values["obs_active_fps"] != 60 || values["obs_render_time_ms"] != 4 || values["obs_disk_space_mb"] != 2000 {
+		t.Errorf("Unexpected render stats %v", values)
+	}
+	if values["render_frames"] != 0 || values["encoder_frames"] != 0 {
+		t.Errorf("Expected no deltas in the first window, got %v", values)
+	}
+
+	obs.updateRenderStats(&general.GetStatsResponse{ActiveFps: 45, AverageFrameRenderTime: 12, AvailableDiskSpace: 1900,
+		RenderSkippedFrames: 8, RenderTotalFrames: 1060, OutputSkippedFrames: 2, OutputTotalFrames: 960})
+	obs.updateRenderStats(&general.GetStatsResponse{ActiveFps: 58, AverageFrameRenderTime: 6, AvailableDiskSpace: 1950,
+		RenderSkippedFrames: 10, RenderTotalFrames: 1120, OutputSkippedFrames: 7, OutputTotalFrames: 1020})
+
+	values = sampleValues(mustCollect(t, obs))
+	if values["obs_active_fps"] != 45 || values["obs_render_time_ms"] != 12 || values["obs_disk_space_mb"] != 1900 {
+		t.Errorf("Expected the lowest fps and disk space and the highest render time, got %v", values)
+	}
+	if values["render_skipped_frames"] != 5 || values["render_frames"] != 120 {
+		t.Errorf("Expected render deltas 5 of 120, got %v and %v", values["render_skipped_frames"], values["render_frames"])
+	}
+	if values["encoder_skipped_frames"] != 5 || values["encoder_frames"] != 120 {
+		t.Errorf("Expected encoder deltas 5 of 120, got %v and %v", values["encoder_skipped_frames"], values["encoder_frames"])
+	}
+}
+
+func TestObsStats_Collect_EncoderCountReset(t *testing.T) {
+	obs := &ObsStats{done: make(chan struct{})}
+	obs.updateRenderStats(&general.GetStatsResponse{OutputTotalFrames: 900})
+	sampleValues(mustCollect(t, obs))
+
+	// The encoder counts drop when an output stops
+	obs.updateRenderStats(&general.GetStatsResponse{OutputTotalFrames: 30})
+	values := sampleValues(mustCollect(t, obs))
+	if values["encoder_frames"] != 30 {
+		t.Errorf("Expected the count since the reset, got %v", values["encoder_frames"])
+	}
+}
+
+func TestObsStats_Collect_NoRenderStats(t *testing.T) {
+	obs := &ObsStats{done: make(chan struct{})}
+	obs.recordError(fmt.Errorf("stats fetch error"))
+
+	samples, err := obs.Collect()
+	if err == nil {
+		t.Error("Expected the error to be returned")
+	}
+	for _, s := range samples {
+		if (s.Name == "obs_active_fps" || s.Name == "obs_render_time_ms" || s.Name == "obs_disk_space_mb") && s.Valid {
+			t.Errorf("Expected %s to be missing without render stats", s.Name)
+		}
+	}
+}
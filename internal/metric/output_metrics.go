@@ -0,0 +1,302 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andreykaipov/goobs/api/requests/outputs"
+)
+
+// OutputMetrics follows every output of OBS, e.g. the extra outputs of multistream plugins.
+// Every output gets a group of columns named after the output, e.g. output_adv_stream_bytes.
+type OutputMetrics struct {
+	client *ObsClient
+	// outputs holds the state per output name, order holds the names in the order they were discovered
+	outputs map[string]*outputState
+	order   []string
+	// columns holds the column names in use, so outputs whose names give the same column name get a suffix
+	columns   map[string]bool
+	lastError error
+	mu        sync.Mutex
+	interval  time.Duration
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+type outputState struct {
+	columns outputColumns
+	// active is set when the output was active within the window, lastActive holds the latest state
+	active        bool
+	lastActive    bool
+	bytes         deltaCounter
+	frames        deltaCounter
+	skippedFrames deltaCounter
+	maxCongestion float64
+	reconnecting  bool
+}
+
+type outputColumns struct {
+	active        Descriptor
+	bytes         Descriptor
+	skippedFrames Descriptor
+	frames        Descriptor
+	congestion    Descriptor
+	reconnecting  Descriptor
+}
+
+// newOutputColumns returns the columns of the output name, id is the unique column name of the output
+func newOutputColumns(name, id string) outputColumns {
+	prefix := "output_" + id + "_"
+	labels := map[string]string{"output": name}
+	column := func(suffix, help, unit string, kind Kind, precision int) Descriptor {
+		return Descriptor{
+			Name:      prefix + suffix,
+			Help:      help,
+			Unit:      unit,
+			Kind:      kind,
+			Precision: precision,
+			Family:    "obs_output_" + suffix,
+			Labels:    labels,
+		}
+	}
+
+	return outputColumns{
+		active:        column("active", "Whether the OBS output was active.", "", Bool, 0),
+		bytes:         column("bytes", "Bytes sent by the OBS output.", "bytes", Counter, 0),
+		skippedFrames: column("skipped_frames", "Frames skipped by the OBS output.", "frames", Counter, 0),
+		frames:        column("frames", "Frames delivered by the OBS output.", "frames", Counter, 0),
+		congestion:    column("congestion", "Highest congestion of the OBS output, from 0 to 1.", "", Gauge, 2),
+		reconnecting:  column("reconnecting", "Whether the OBS output was reconnecting to the server.", "", Bool, 0),
+	}
+}
+
+func (c outputColumns) list() []Descriptor {
+	return []Descriptor{c.active, c.bytes, c.skippedFrames, c.frames, c.congestion, c.reconnecting}
+}
+
+// takesStreamColumn reports whether the columns of an output with the column name id would have the name of
+// a column of the stream, e.g. output_skipped_frames of the output "skipped"
+func takesStreamColumn(id string) bool {
+	for _, c := range newOutputColumns("", id).list() {
+		if slices.ContainsFunc((&StreamMetrics{}).Describe(), func(d Descriptor) bool { return d.Name == c.Name }) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnName turns an output name into a column name, e.g. "Aitum Multi Output 1" into aitum_multi_output_1
+func columnName(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// NewOutputMetrics creates the output collector, the outputs that OBS has now are discovered right away,
+// so their columns are part of the columns the writers start with
+func NewOutputMetrics(client *ObsClient, interval time.Duration) (*OutputMetrics, error) {
+	o := &OutputMetrics{
+		client:   client,
+		interval: interval,
+		outputs:  map[string]*outputState{},
+		columns:  map[string]bool{},
+		done:     make(chan struct{}),
+	}
+	if _, err := o.discover(); err != nil {
+		o.recordError(err)
+	}
+	return o, nil
+}
+
+func (o *OutputMetrics) Name() string {
+	return "outputs"
+}
+
+// Describe returns the columns of the outputs discovered so far
+func (o *OutputMetrics) Describe() []Descriptor {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	columns := []Descriptor{}
+	for _, name := range o.order {
+		columns = append(columns, o.outputs[name].columns.list()...)
+	}
+	return columns
+}
+
+// Collect returns the state and the counter deltas of every output, the values of inactive outputs are missing
+func (o *OutputMetrics) Collect() ([]Sample, error) {
+	if o.stopped() {
+		return nil, nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	samples := []Sample{}
+	for _, name := range o.order {
+		state := o.outputs[name]
+		c := state.columns
+
+		samples = append(samples, c.active.BoolSample(state.active))
+		bytes, frames, skipped := state.bytes.delta(), state.frames.delta(), state.skippedFrames.delta()
+		if state.active {
+			samples = append(samples,
+				c.bytes.Sample(bytes),
+				c.skippedFrames.Sample(skipped),
+				c.frames.Sample(frames),
+				c.congestion.Sample(state.maxCongestion),
+				c.reconnecting.BoolSample(state.reconnecting),
+			)
+		}
+
+		state.active = state.lastActive
+		state.maxCongestion = 0
+		state.reconnecting = false
+	}
+
+	err := o.lastError
+	o.lastError = nil
+	return samples, err
+}
+
+// discover adds the outputs that are new since the previous call and returns the active outputs
+func (o *OutputMetrics) discover() ([]string, error) {
+	o.client.Lock()
+	list, err := o.client.Outputs.GetOutputList()
+	o.client.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outputs: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	active := []string{}
+	for _, output := range list.Outputs {
+		state, ok := o.outputs[output.Name]
+		if !ok {
+			state = o.add(output.Name)
+		}
+		if output.Active {
+			active = append(active, output.Name)
+		} else {
+			state.lastActive = false
+		}
+	}
+	return active, nil
+}
+
+// add follows a new output, it gets a suffix when its column name is taken by another output,
+// e.g. "Stream" and "stream" get the columns output_stream_* and output_stream_2_*
+func (o *OutputMetrics) add(name string) *outputState {
+	base := columnName(name)
+	if base == "" {
+		base = "unnamed"
+	}
+	column := base
+	for i := 2; o.columns[column] || takesStreamColumn(column); i++ {
+		column = fmt.Sprintf("%s_%d", base, i)
+	}
+	o.columns[column] = true
+
+	state := &outputState{columns: newOutputColumns(name, column)}
+	o.outputs[name] = state
+	o.order = append(o.order, name)
+	return state
+}
+
+func (o *OutputMetrics) updateOutput(name string, status *outputs.GetOutputStatusResponse) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state, ok := o.outputs[name]
+	if !ok {
+		return
+	}
+
+	if status.OutputActive {
+		state.active = true
+	}
+	state.lastActive = status.OutputActive
+	state.bytes.update(status.OutputBytes)
+	state.frames.update(status.OutputTotalFrames)
+	state.skippedFrames.update(status.OutputSkippedFrames)
+	if status.OutputCongestion > state.maxCongestion {
+		state.maxCongestion = status.OutputCongestion
+	}
+	if status.OutputReconnecting {
+		state.reconnecting = true
+	}
+}
+
+func (o *OutputMetrics) recordError(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.lastError = err
+}
+
+func (o *OutputMetrics) Start(ctx context.Context) error {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-o.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		active, err := o.discover()
+		if err != nil {
+			o.recordError(err)
+			continue
+		}
+
+		// Only active outputs are queried, OBS keeps the status of a stopped output until it starts again
+		var errs []error
+		for _, name := range active {
+			o.client.Lock()
+			status, err := o.client.Outputs.GetOutputStatus(&outputs.GetOutputStatusParams{OutputName: &name})
+			o.client.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to get status of output %s: %w", name, err))
+				continue
+			}
+			o.updateOutput(name, status)
+		}
+		if err := errors.Join(errs...); err != nil {
+			o.recordError(err)
+		}
+	}
+}
+
+// Stop ends the collection loop started by Start, e.g. when the OBS connection is lost
+func (o *OutputMetrics) Stop() {
+	o.stopOnce.Do(func() { close(o.done) })
+}
+
+func (o *OutputMetrics) stopped() bool {
+	select {
+	case <-o.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,134 @@
+package metric
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andreykaipov/goobs/api/requests/outputs"
+)
+
+func newTestOutputMetrics(names ...string) *OutputMetrics {
+	o := &OutputMetrics{outputs: map[string]*outputState{}, columns: map[string]bool{}, done: make(chan struct{})}
+	for _, name := range names {
+		o.add(name)
+	}
+	return o
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[string]string{
+		"adv_stream":            "adv_stream",
+		"Aitum Multi Output 1":  "aitum_multi_output_1",
+		"Source Record (Cam 2)": "source_record_cam_2",
+		"  Twitch--Backup ":     "twitch_backup",
+	}
+	for name, expected := range tests {
+		if got := columnName(name); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, name, got)
+		}
+	}
+}
+
+func TestOutputMetrics_Describe(t *testing.T) {
+	o := newTestOutputMetrics("adv_stream", "Aitum Multi Output 1")
+
+	names := []string{}
+	for _, c := range o.Describe() {
+		names = append(names, c.Name)
+	}
+	expected := []string{
+		"output_adv_stream_active", "output_adv_stream_bytes", "output_adv_stream_skipped_frames",
+		"output_adv_stream_frames", "output_adv_stream_congestion", "output_adv_stream_reconnecting",
+		"output_aitum_multi_output_1_active", "output_aitum_multi_output_1_bytes", "output_aitum_multi_output_1_skipped_frames",
+		"output_aitum_multi_output_1_frames", "output_aitum_multi_output_1_congestion", "output_aitum_multi_output_1_reconnecting",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected columns %v, got %v", expected, names)
+	}
+
+	bytes := o.Describe()[7]
+	if bytes.Family != "obs_output_bytes" || bytes.Labels["output"] != "Aitum Multi Output 1" {
+		t.Errorf("Expected the bytes family labeled with the output name, got %+v", bytes)
+	}
+}
+
+func TestOutputMetrics_Describe_SameColumnName(t *testing.T) {
+	o := newTestOutputMetrics("Stream", "stream", "#1", "Skipped")
+
+	names := []string{}
+	for _, c := range o.Describe() {
+		if c.Family == "obs_output_bytes" {
+			names = append(names, c.Name)
+		}
+	}
+	// The frames column of the output "Skipped" would be output_skipped_frames, the column of the stream
+	expected := []string{"output_stream_bytes", "output_stream_2_bytes", "output_1_bytes", "output_skipped_2_bytes"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected a column per output %v, got %v", expected, names)
+	}
+}
+
+func TestOutputMetrics_Collect(t *testing.T) {
+	o := newTestOutputMetrics("adv_stream", "Aitum Multi Output 1")
+	o.updateOutput("Aitum Multi Output 1", &outputs.GetOutputStatusResponse{OutputActive: true, OutputBytes: 1000, OutputTotalFrames: 60})
+	mustCollect(t, o)
+
+	o.updateOutput("Aitum Multi Output 1", &outputs.GetOutputStatusResponse{OutputActive: true, OutputBytes: 250_000,
+		OutputTotalFrames: 90, OutputSkippedFrames: 2, OutputCongestion: 0.6, OutputReconnecting: true})
+	o.updateOutput("Aitum Multi Output 1", &outputs.GetOutputStatusResponse{OutputActive: true, OutputBytes: 500_000,
+		OutputTotalFrames: 120, OutputSkippedFrames: 3, OutputCongestion: 0.2})
+
+	samples := mustCollect(t, o)
+	values := sampleValues(samples)
+	prefix := "output_aitum_multi_output_1_"
+	if values[prefix+"active"] != 1 || values[prefix+"bytes"] != 499_000 || values[prefix+"frames"] != 60 || values[prefix+"skipped_frames"] != 3 {
+		t.Errorf("Expected the deltas of the active output, got %v", values)
+	}
+	if values[prefix+"congestion"] != 0.6 || values[prefix+"reconnecting"] != 1 {
+		t.Errorf("Expected the highest congestion and the reconnect, got %v", values)
+	}
+
+	// The inactive output only reports that it is inactive
+	if values["output_adv_stream_active"] != 0 {
+		t.Errorf("Expected adv_stream to be inactive, got %v", values["output_adv_stream_active"])
+	}
+	if _, ok := values["output_adv_stream_bytes"]; ok {
+		t.Error("Expected no bytes of an inactive output")
+	}
+}
+
+func TestOutputMetrics_Collect_ResetsWindow(t *testing.T) {
+	o := newTestOutputMetrics("adv_stream")
+	o.updateOutput("adv_stream", &outputs.GetOutputStatusResponse{OutputActive: true, OutputCongestion: 0.9, OutputReconnecting: true})
+	mustCollect(t, o)
+
+	values := sampleValues(mustCollect(t, o))
+	if values["output_adv_stream_active"] != 1 {
+		t.Error("Expected the output to stay active without measurements")
+	}
+	if values["output_adv_stream_congestion"] != 0 || values["output_adv_stream_reconnecting"] != 0 {
+		t.Errorf("Expected the congestion and reconnect to be reset, got %v", values)
+	}
+}
+
+func TestOutputMetrics_Collect_Error(t *testing.T) {
+	o := newTestOutputMetrics()
+	o.recordError(fmt.Errorf("output list error"))
+
+	if _, err := o.Collect(); err == nil {
+		t.Error("Expected the error to be returned")
+	}
+	if _, err := o.Collect(); err != nil {
+		t.Errorf("Expected the error to be reset, got %v", err)
+	}
+}
+
+func TestOutputMetrics_Collect_StoppedHasNoSamples(t *testing.T) {
+	o := newTestOutputMetrics("adv_stream")
+	o.Stop()
+
+	if samples := mustCollect(t, o); len(samples) != 0 {
+		t.Errorf("Expected no samples from a stopped collector, got %d", len(samples))
+	}
+}
@@ -1,7 +1,8 @@
 package metric
 
 import (
-	"fmt"
+	"context"
+	"math"
 	"runtime"
 	"sync"
 	"time"
@@ -9,12 +10,30 @@ import (
 	probing "github.com/prometheus-community/pro-bing"
 )
 
+// pingLossTimeout is how long a ping may go unanswered before it counts as lost
+const pingLossTimeout = 2 * time.Second
+
+// Pinger sends pings to a target continuously and reports RTT, packet loss and jitter per writer interval
 type Pinger struct {
-	domain    string
-	maxRTT    time.Duration
+	name   string
+	domain string
+	maxRTT time.Duration
+	minRTT time.Duration
+	rtts   Window
+	// session summarizes the RTTs since the start, for the summary of the monitoring session
+	session   Histogram
 	lastError error
-	mu        sync.Mutex
-	interval  time.Duration
+	// pending holds the send time of every ping that is not answered or lost yet, by sequence number
+	pending  map[int]time.Time
+	received int
+	lost     int
+	// jitter is the RFC 3550 interarrival jitter estimate, it is kept across windows
+	jitter       float64
+	lastRTT      time.Duration
+	hasLastRTT   bool
+	mu           sync.Mutex
+	interval     time.Duration
+	aggregations []Aggregation
 }
 
 type PingMetrics struct {
@@ -23,13 +42,115 @@ type PingMetrics struct {
 	Error     error
 }
 
-func NewPinger(domain string, interval time.Duration) (*Pinger, error) {
+// NewPinger creates a pinger for domain, name is used as prefix for its columns, e.g. obs_rtt_ms.
+// The aggregations of the RTTs within a writer interval are added as extra columns.
+func NewPinger(name, domain string, interval time.Duration, aggregations []Aggregation) (*Pinger, error) {
 	return &Pinger{
-		domain:   domain,
-		interval: interval,
+		name:         name,
+		domain:       domain,
+		interval:     interval,
+		aggregations: aggregations,
 	}, nil
 }
 
+// NewPingerPlaceholder declares the columns of a pinger whose target is not known yet
+func NewPingerPlaceholder(name string, aggregations []Aggregation) *Placeholder {
+	return NewPlaceholder(&Pinger{name: name, aggregations: aggregations})
+}
+
+func (p *Pinger) Name() string {
+	return p.name + "_ping"
+}
+
+func (p *Pinger) Describe() []Descriptor {
+	columns := append([]Descriptor{p.rttColumn()}, aggregatedColumns(p.rttColumn(), p.aggregations)...)
+	return append(columns, p.minRTTColumn(), p.stdDevColumn(), p.lossColumn(), p.jitterColumn())
+}
+
+func (p *Pinger) rttColumn() Descriptor {
+	return p.column("rtt_ms", "Maximum ping round-trip time within the writer interval.", "ms")
+}
+
+func (p *Pinger) minRTTColumn() Descriptor {
+	return p.column("rtt_min_ms", "Minimum ping round-trip time within the writer interval.", "ms")
+}
+
+func (p *Pinger) stdDevColumn() Descriptor {
+	return p.column("rtt_stddev_ms", "Standard deviation of the ping round-trip times within the writer interval.", "ms")
+}
+
+func (p *Pinger) lossColumn() Descriptor {
+	return p.column("loss_pct", "Percentage of pings lost within the writer interval.", "percent")
+}
+
+func (p *Pinger) jitterColumn() Descriptor {
+	return p.column("jitter_ms", "Interarrival jitter of the ping round-trip times as defined in RFC 3550.", "ms")
+}
+
+func (p *Pinger) column(family, help, unit string) Descriptor {
+	return Descriptor{
+		Name:      p.name + "_" + family,
+		Help:      help,
+		Unit:      unit,
+		Kind:      Gauge,
+		Precision: 2,
+		Family:    family,
+		Labels:    map[string]string{"target": p.name},
+	}
+}
+
+// Collect returns the RTT statistics of the pings answered within the window.
+// Pings that are unanswered for longer than pingLossTimeout count as lost in the window they expire in.
+func (p *Pinger) Collect() ([]Sample, error) {
+	p.mu.Lock()
+	p.expireLost(time.Now())
+
+	maxRTT := p.maxRTT
+	minRTT := p.minRTT
+	rtts := p.rtts.Take()
+	received := p.received
+	lost := p.lost
+	jitter := p.jitter
+	hasJitter := p.hasLastRTT
+	err := p.lastError
+
+	p.maxRTT = 0
+	p.minRTT = 0
+	p.received = 0
+	p.lost = 0
+	p.lastError = nil
+	p.mu.Unlock()
+
+	samples := aggregatedSamples(p.rttColumn(), p.aggregations, rtts)
+	if maxRTT > 0 {
+		samples = append(samples,
+			p.rttColumn().Sample(durationMs(maxRTT)),
+			p.minRTTColumn().Sample(durationMs(minRTT)),
+			p.stdDevColumn().Sample(stdDev(rtts)),
+		)
+	}
+	if received+lost > 0 {
+		samples = append(samples, p.lossColumn().Sample(float64(lost)/float64(received+lost)*100))
+	}
+	if hasJitter {
+		samples = append(samples, p.jitterColumn().Sample(jitter))
+	}
+	return samples, err
+}
+
+// Target returns the name of the pinged target, the prefix of its columns
+func (p *Pinger) Target() string {
+	return p.name
+}
+
+// SessionStats summarizes the RTTs of all pings answered since the start
+func (p *Pinger) SessionStats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.session.Stats()
+}
+
+// GetAndResetMaxRTT returns the highest RTT of the window and the last error, and starts a new window like Collect
 func (p *Pinger) GetAndResetMaxRTT() (time.Duration, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -38,51 +159,146 @@ func (p *Pinger) GetAndResetMaxRTT() (time.Duration, error) {
 	err := p.lastError
 
 	p.maxRTT = 0
+	p.minRTT = 0
+	p.rtts.Take()
+	p.received = 0
+	p.lost = 0
 	p.lastError = nil
 
 	return maxRTT, err
 }
 
-func (p *Pinger) Start() error {
-	fmt.Printf("Pinging %s every %v\n", p.domain, p.interval)
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000.0
+}
 
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+// stdDev returns the population standard deviation of values
+func stdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
 
-	for range ticker.C {
-		rtt, err := p.ping(p.domain)
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
 
-		p.mu.Lock()
+// Start pings the target until ctx is done.
+// When the target can't be resolved or pinged, it retries after the interval.
+func (p *Pinger) Start(ctx context.Context) error {
+	for {
+		err := p.run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
 		if err != nil {
-			p.lastError = err
-		} else if rtt > p.maxRTT {
-			p.maxRTT = rtt
+			p.recordError(err)
 		}
-		p.mu.Unlock()
-	}
 
-	return nil
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(p.interval):
+		}
+	}
 }
 
-func (p *Pinger) ping(domain string) (time.Duration, error) {
-	pinger, err := probing.NewPinger(domain)
-	if err != nil {
-		return 0, err
+// run sends pings with a single long-lived pinger until ctx is done or the pinger fails
+func (p *Pinger) run(ctx context.Context) error {
+	// Bound the DNS lookup as well, so a shutdown never waits on a hanging resolver
+	pinger := probing.New(p.domain)
+	pinger.ResolveTimeout = 1 * time.Second
+	if err := pinger.Resolve(); err != nil {
+		return err
 	}
 
-	pinger.Count = 1
-	pinger.Timeout = 1 * time.Second
+	pinger.Interval = p.interval
+	pinger.RecordRtts = false
+	pinger.RecordTTLs = false
 	pinger.SetPrivileged(runtime.GOOS == "windows")
+	pinger.SetLogger(probing.NoopLogger{})
 
-	err = pinger.Run()
-	if err != nil {
-		return 0, err
+	pinger.OnSend = func(pkt *probing.Packet) {
+		p.recordSent(pkt.Seq, time.Now())
+	}
+	pinger.OnSendError = func(pkt *probing.Packet, err error) {
+		p.recordSendError(err)
 	}
+	pinger.OnRecv = func(pkt *probing.Packet) {
+		p.recordReceived(pkt.Seq, pkt.Rtt)
+	}
+
+	return pinger.RunWithContext(ctx)
+}
+
+func (p *Pinger) recordSent(seq int, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	stats := pinger.Statistics()
-	if stats.PacketsRecv == 0 {
-		return 0, fmt.Errorf("no response received")
+	if p.pending == nil {
+		p.pending = map[int]time.Time{}
 	}
+	p.pending[seq] = at
+}
+
+// recordSendError counts a ping that could not be sent as lost
+func (p *Pinger) recordSendError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	return stats.AvgRtt, nil
+	p.lost++
+	p.lastError = err
+}
+
+func (p *Pinger) recordReceived(seq int, rtt time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pending[seq]; !ok {
+		// Answered after it was counted as lost
+		return
+	}
+	delete(p.pending, seq)
+
+	p.received++
+	p.rtts.Add(durationMs(rtt))
+	p.session.Add(durationMs(rtt))
+	if rtt > p.maxRTT {
+		p.maxRTT = rtt
+	}
+	if p.minRTT == 0 || rtt < p.minRTT {
+		p.minRTT = rtt
+	}
+
+	// RFC 3550: J += (|D| - J) / 16, where D is the difference in transit time of consecutive packets
+	if p.hasLastRTT {
+		d := math.Abs(durationMs(rtt) - durationMs(p.lastRTT))
+		p.jitter += (d - p.jitter) / 16
+	}
+	p.lastRTT = rtt
+	p.hasLastRTT = true
+}
+
+// expireLost counts the pings that are unanswered for longer than pingLossTimeout as lost
+func (p *Pinger) expireLost(now time.Time) {
+	for seq, sentAt := range p.pending {
+		if now.Sub(sentAt) > pingLossTimeout {
+			delete(p.pending, seq)
+			p.lost++
+		}
+	}
+}
+
+func (p *Pinger) recordError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastError = err
 }
@@ -2,6 +2,8 @@ package metric
 
 import (
 	"fmt"
+	"math"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -117,7 +119,7 @@ func TestPinger_NewPinger(t *testing.T) {
 	domain := "example.com"
 	interval := 1 * time.Second
 
-	p, err := NewPinger(domain, interval)
+	p, err := NewPinger("test", domain, interval, nil)
 
 	if err != nil {
 		t.Fatalf("NewPinger returned error: %v", err)
@@ -163,3 +165,174 @@ func TestPinger_GetAndResetMaxRTT_MultipleResets(t *testing.T) {
 		t.Errorf("Expected second call to return 0 after reset, got %v", rtt2)
 	}
 }
+
+func sampleValues(samples []Sample) map[string]float64 {
+	values := map[string]float64{}
+	for _, s := range samples {
+		values[s.Name] = s.Value
+	}
+	return values
+}
+
+func TestPinger_Collect_NamedColumns(t *testing.T) {
+	p := &Pinger{name: "obs"}
+	p.recordSent(1, time.Now())
+	p.recordReceived(1, 50*time.Millisecond)
+
+	if p.Name() != "obs_ping" {
+		t.Errorf("Expected name obs_ping, got %s", p.Name())
+	}
+
+	names := []string{}
+	for _, c := range p.Describe() {
+		names = append(names, c.Name)
+	}
+	expected := []string{"obs_rtt_ms", "obs_rtt_min_ms", "obs_rtt_stddev_ms", "obs_loss_pct", "obs_jitter_ms"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("Expected columns %v, got %v", expected, names)
+	}
+
+	samples, err := p.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	values := sampleValues(samples)
+	if values["obs_rtt_ms"] != 50 || values["obs_rtt_min_ms"] != 50 || values["obs_loss_pct"] != 0 {
+		t.Errorf("Unexpected samples %v", values)
+	}
+
+	samples, _ = p.Collect()
+	values = sampleValues(samples)
+	if _, ok := values["obs_rtt_ms"]; ok {
+		t.Errorf("Expected no RTT without an answered ping, got %v", values)
+	}
+	if _, ok := values["obs_loss_pct"]; ok {
+		t.Errorf("Expected no loss without pings, got %v", values)
+	}
+}
+
+func TestPinger_Collect_Aggregations(t *testing.T) {
+	p := &Pinger{
+		name:         "obs",
+		aggregations: []Aggregation{AggregateAvg, AggregateCount},
+	}
+	p.recordSent(1, time.Now())
+	p.recordReceived(1, 10*time.Millisecond)
+	p.recordSent(2, time.Now())
+	p.recordReceived(2, 30*time.Millisecond)
+
+	samples, err := p.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	values := sampleValues(samples)
+	if values["obs_rtt_ms"] != 30 || values["obs_rtt_ms_avg"] != 20 || values["obs_rtt_ms_count"] != 2 {
+		t.Errorf("Unexpected samples %v", values)
+	}
+	if values["obs_rtt_min_ms"] != 10 || values["obs_rtt_stddev_ms"] != 10 {
+		t.Errorf("Expected min 10 and stddev 10, got %v", values)
+	}
+}
+
+func TestPinger_Collect_PacketLoss(t *testing.T) {
+	p := &Pinger{name: "obs"}
+	now := time.Now()
+
+	// Two answered, one expired and one still in flight
+	p.recordSent(1, now.Add(-5*time.Second))
+	p.recordReceived(1, 20*time.Millisecond)
+	p.recordSent(2, now.Add(-4*time.Second))
+	p.recordSent(3, now.Add(-3*time.Second))
+	p.recordReceived(3, 20*time.Millisecond)
+	p.recordSent(4, now)
+
+	samples, _ := p.Collect()
+	values := sampleValues(samples)
+	if got := values["obs_loss_pct"]; math.Abs(got-100.0/3) > 1e-9 {
+		t.Errorf("Expected 33.33%% loss, got %v", got)
+	}
+
+	// The in-flight ping is answered in the next window, a late answer to the expired one is ignored
+	p.recordReceived(4, 25*time.Millisecond)
+	p.recordReceived(2, 3*time.Second)
+
+	samples, _ = p.Collect()
+	values = sampleValues(samples)
+	if values["obs_loss_pct"] != 0 {
+		t.Errorf("Expected 0%% loss, got %v", values["obs_loss_pct"])
+	}
+	if values["obs_rtt_ms"] != 25 {
+		t.Errorf("Expected late answer to be ignored, got max RTT %v", values["obs_rtt_ms"])
+	}
+}
+
+func TestPinger_Collect_SendErrorCountsAsLoss(t *testing.T) {
+	p := &Pinger{name: "obs"}
+	p.recordSendError(fmt.Errorf("network is unreachable"))
+
+	samples, err := p.Collect()
+	if err == nil {
+		t.Error("Expected send error to be reported")
+	}
+	if values := sampleValues(samples); values["obs_loss_pct"] != 100 {
+		t.Errorf("Expected 100%% loss, got %v", values)
+	}
+}
+
+func TestPinger_Jitter_RFC3550(t *testing.T) {
+	p := &Pinger{name: "obs"}
+	for i, rtt := range []time.Duration{10, 26, 10, 26} {
+		p.recordSent(i, time.Now())
+		p.recordReceived(i, rtt*time.Millisecond)
+	}
+
+	// J1 = 16/16 = 1, J2 = 1 + (16-1)/16, J3 = J2 + (16-J2)/16
+	j := 1.0
+	j += (16 - j) / 16
+	j += (16 - j) / 16
+
+	samples, _ := p.Collect()
+	if got := sampleValues(samples)["obs_jitter_ms"]; math.Abs(got-j) > 1e-9 {
+		t.Errorf("Expected jitter %v, got %v", j, got)
+	}
+
+	// The estimate is kept across windows
+	samples, _ = p.Collect()
+	if got := sampleValues(samples)["obs_jitter_ms"]; math.Abs(got-j) > 1e-9 {
+		t.Errorf("Expected jitter %v to be kept, got %v", j, got)
+	}
+}
+
+func TestPinger_SessionStats_SpanWindows(t *testing.T) {
+	p := &Pinger{name: "obs"}
+	for i, rtt := range []time.Duration{10, 30} {
+		p.recordSent(i, time.Now())
+		p.recordReceived(i, rtt*time.Millisecond)
+		p.Collect()
+	}
+
+	stats := p.SessionStats()
+	if stats.Count != 2 || stats.Min != 10 || stats.Max != 30 || stats.Avg != 20 {
+		t.Errorf("Expected the RTTs of both windows, got %+v", stats)
+	}
+}
+
+func TestPinger_GetAndResetMaxRTT_StartsNewWindow(t *testing.T) {
+	p := &Pinger{name: "obs"}
+	p.recordSent(1, time.Now())
+	p.recordReceived(1, 40*time.Millisecond)
+
+	if rtt, _ := p.GetAndResetMaxRTT(); rtt != 40*time.Millisecond {
+		t.Errorf("Expected RTT to be 40ms, got %v", rtt)
+	}
+
+	samples, _ := p.Collect()
+	values := sampleValues(samples)
+	if _, ok := values["obs_rtt_ms"]; ok {
+		t.Errorf("Expected the window to be taken, got %v", values)
+	}
+	if _, ok := values["obs_loss_pct"]; ok {
+		t.Errorf("Expected the pings to be taken with the window, got %v", values)
+	}
+}
@@ -0,0 +1,172 @@
+package metric
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+var (
+	recordActiveColumn   = Descriptor{Name: "record_active", Help: "Whether the record output was active.", Kind: Bool}
+	recordPausedColumn   = Descriptor{Name: "record_paused", Help: "Whether the record output is paused.", Kind: Bool}
+	recordBytesColumn    = Descriptor{Name: "record_bytes", Help: "Bytes written by the record output.", Unit: "bytes", Kind: Counter}
+	recordDurationColumn = Descriptor{Name: "record_duration", Help: "Time the record output has been active.", Unit: "seconds", Kind: Gauge}
+)
+
+// RecordMetrics follows the record output of OBS
+type RecordMetrics struct {
+	client *ObsClient
+	// active is set when the recording was active within the window, lastActive holds the latest state
+	active     bool
+	lastActive bool
+	paused     bool
+	bytes      deltaCounter
+	duration   float64
+	lastError  error
+	mu         sync.Mutex
+	interval   time.Duration
+	done       chan struct{}
+	stopOnce   sync.Once
+}
+
+type RecordMetricsData struct {
+	Timestamp time.Time
+	Active    bool
+	Paused    bool
+	// Bytes is the delta since the previous window, 0 for the first window
+	Bytes float64
+	// Duration is the latest duration of the recording in seconds
+	Duration float64
+	Error    error
+}
+
+func NewRecordMetrics(client *ObsClient, interval time.Duration) (*RecordMetrics, error) {
+	return &RecordMetrics{
+		client:   client,
+		interval: interval,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (r *RecordMetrics) Name() string {
+	return "record"
+}
+
+func (r *RecordMetrics) Describe() []Descriptor {
+	return []Descriptor{recordActiveColumn, recordPausedColumn, recordBytesColumn, recordDurationColumn}
+}
+
+// Collect returns the record state and the bytes written, a stopped collector has no samples
+func (r *RecordMetrics) Collect() ([]Sample, error) {
+	if r.stopped() {
+		return nil, nil
+	}
+
+	data := r.GetAndResetMaxValues()
+	return []Sample{
+		recordActiveColumn.BoolSample(data.Active),
+		recordPausedColumn.BoolSample(data.Paused),
+		recordBytesColumn.Sample(data.Bytes),
+		recordDurationColumn.Sample(data.Duration),
+	}, data.Error
+}
+
+func (r *RecordMetrics) GetAndResetMaxValues() RecordMetricsData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := RecordMetricsData{
+		Timestamp: time.Now(),
+		Active:    r.active,
+		Paused:    r.paused,
+		Bytes:     r.bytes.delta(),
+		Duration:  r.duration,
+		Error:     r.lastError,
+	}
+
+	r.active = r.lastActive
+	r.lastError = nil
+
+	return data
+}
+
+func (r *RecordMetrics) updateMetrics(active, paused bool, bytes, durationMs float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setActive(active)
+	r.paused = paused
+	r.bytes.update(bytes)
+	r.duration = durationMs / 1000
+}
+
+// StateChanged follows the RecordStateChanged events of OBS, so a recording that starts and stops between
+// two measurements still marks the window as recording
+func (r *RecordMetrics) StateChanged(state string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch state {
+	case "OBS_WEBSOCKET_OUTPUT_STARTED":
+		r.setActive(true)
+	case "OBS_WEBSOCKET_OUTPUT_STOPPED":
+		r.setActive(false)
+		r.paused = false
+	case "OBS_WEBSOCKET_OUTPUT_PAUSED":
+		r.paused = true
+	case "OBS_WEBSOCKET_OUTPUT_RESUMED":
+		r.paused = false
+	}
+}
+
+func (r *RecordMetrics) setActive(active bool) {
+	if active {
+		r.active = true
+	}
+	r.lastActive = active
+}
+
+func (r *RecordMetrics) recordError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastError = err
+}
+
+func (r *RecordMetrics) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		r.client.Lock()
+		status, err := r.client.Record.GetRecordStatus()
+		r.client.Unlock()
+		if err != nil {
+			r.recordError(err)
+			continue
+		}
+
+		r.updateMetrics(status.OutputActive, status.OutputPaused, status.OutputBytes, status.OutputDuration)
+	}
+}
+
+// Stop ends the collection loop started by Start, e.g. when the OBS connection is lost
+func (r *RecordMetrics) Stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
+
+func (r *RecordMetrics) stopped() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+package metric
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRecordMetrics_Collect(t *testing.T) {
+	rm := &RecordMetrics{done: make(chan struct{})}
+	rm.updateMetrics(true, false, 1_000_000, 10_000)
+
+	values := sampleValues(mustCollect(t, rm))
+	if values["record_active"] != 1 || values["record_paused"] != 0 {
+		t.Errorf("Expected an active recording, got %v", values)
+	}
+	if values["record_bytes"] != 0 || values["record_duration"] != 10 {
+		t.Errorf("Expected no bytes in the first window and 10s, got %v", values)
+	}
+
+	rm.updateMetrics(true, true, 1_500_000, 11_000)
+	values = sampleValues(mustCollect(t, rm))
+	if values["record_bytes"] != 500_000 || values["record_paused"] != 1 {
+		t.Errorf("Expected 500000 bytes while paused, got %v", values)
+	}
+}
+
+func TestRecordMetrics_Collect_NewRecording(t *testing.T) {
+	rm := &RecordMetrics{done: make(chan struct{})}
+	rm.updateMetrics(true, false, 5_000_000, 60_000)
+	mustCollect(t, rm)
+
+	// The bytes start at 0 again for the next recording
+	rm.updateMetrics(true, false, 200_000, 1_000)
+	values := sampleValues(mustCollect(t, rm))
+	if values["record_bytes"] != 200_000 {
+		t.Errorf("Expected the bytes of the new recording, got %v", values["record_bytes"])
+	}
+}
+
+func TestRecordMetrics_StateChanged_ShortRecording(t *testing.T) {
+	rm := &RecordMetrics{done: make(chan struct{})}
+	rm.updateMetrics(false, false, 0, 0)
+
+	// A recording that started and stopped between two measurements
+	rm.StateChanged("OBS_WEBSOCKET_OUTPUT_STARTED")
+	rm.StateChanged("OBS_WEBSOCKET_OUTPUT_STOPPED")
+
+	values := sampleValues(mustCollect(t, rm))
+	if values["record_active"] != 1 {
+		t.Error("Expected the short recording to mark the window as recording")
+	}
+
+	values = sampleValues(mustCollect(t, rm))
+	if values["record_active"] != 0 {
+		t.Error("Expected the next window to be idle")
+	}
+}
+
+func TestRecordMetrics_StateChanged_StaysActive(t *testing.T) {
+	rm := &RecordMetrics{done: make(chan struct{})}
+	rm.StateChanged("OBS_WEBSOCKET_OUTPUT_STARTED")
+	rm.StateChanged("OBS_WEBSOCKET_OUTPUT_PAUSED")
+	mustCollect(t, rm)
+
+	values := sampleValues(mustCollect(t, rm))
+	if values["record_active"] != 1 || values["record_paused"] != 1 {
+		t.Errorf("Expected the recording to stay active and paused without measurements, got %v", values)
+	}
+}
+
+func TestRecordMetrics_Collect_Error(t *testing.T) {
+	rm := &RecordMetrics{done: make(chan struct{})}
+	rm.recordError(fmt.Errorf("record status error"))
+
+	if _, err := rm.Collect(); err == nil {
+		t.Error("Expected the error to be returned")
+	}
+	if _, err := rm.Collect(); err != nil {
+		t.Errorf("Expected the error to be reset, got %v", err)
+	}
+}
+
+func TestRecordMetrics_Collect_StoppedHasNoSamples(t *testing.T) {
+	rm := &RecordMetrics{done: make(chan struct{})}
+	rm.updateMetrics(true, false, 1000, 1000)
+	rm.Stop()
+
+	if samples := mustCollect(t, rm); len(samples) != 0 {
+		t.Errorf("Expected no samples from a stopped collector, got %d", len(samples))
+	}
+}
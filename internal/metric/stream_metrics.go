@@ -1,15 +1,26 @@
 package metric
 
 import (
-	"fmt"
+	"context"
 	"sync"
 	"time"
+)
 
-	"github.com/andreykaipov/goobs"
+var (
+	streamActiveColumn        = Descriptor{Name: "stream_active", Help: "Whether the stream output is active.", Kind: Bool}
+	outputBytesColumn         = Descriptor{Name: "output_bytes", Help: "Bytes sent by the stream output.", Unit: "bytes", Kind: Counter}
+	outputSkippedFramesColumn = Descriptor{Name: "output_skipped_frames", Help: "Frames skipped by the stream output.", Unit: "frames", Kind: Counter}
+	outputFramesColumn        = Descriptor{Name: "output_frames", Help: "Frames delivered by the stream output.", Unit: "frames", Kind: Counter}
+	outputKbpsColumn          = Descriptor{Name: "output_kbps", Help: "Bitrate of the stream output in kilobits per second.", Unit: "kbps", Kind: Gauge}
+	outputFpsColumn           = Descriptor{Name: "output_fps", Help: "Frames per second delivered by the stream output.", Unit: "fps", Kind: Gauge, Precision: 2}
+	outputCongestionColumn    = Descriptor{Name: "output_congestion", Help: "Highest congestion of the stream output reported by OBS, from 0 to 1.", Kind: Gauge, Precision: 2}
+	outputReconnectingColumn  = Descriptor{Name: "output_reconnecting", Help: "Whether the stream output was reconnecting to the server.", Kind: Bool}
+	outputReconnectsColumn    = Descriptor{Name: "output_reconnects", Help: "Reconnects of the stream output to the server.", Unit: "reconnects", Kind: Counter}
+	outputDurationColumn      = Descriptor{Name: "output_duration", Help: "Time the stream output has been active.", Unit: "seconds", Kind: Gauge}
 )
 
 type StreamMetrics struct {
-	client            *goobs.Client
+	client            *ObsClient
 	maxOutputBytes    float64
 	prevOutputBytes   float64
 	maxSkippedFrames  float64
@@ -17,10 +28,20 @@ type StreamMetrics struct {
 	maxTotalFrames    float64
 	prevTotalFrames   float64
 	lastActive        bool
-	lastError         error
-	measurementCount  int
-	mu                sync.Mutex
-	interval          time.Duration
+	maxCongestion     float64
+	// reconnecting is set when the output was reconnecting within the window, lastReconnecting holds the latest state
+	reconnecting     bool
+	lastReconnecting bool
+	reconnects       int
+	duration         float64
+	lastError        error
+	measurementCount int
+	// lastCollect is the time of the previous GetAndResetMaxValues call, the deltas cover the time since
+	lastCollect time.Time
+	mu          sync.Mutex
+	interval    time.Duration
+	done        chan struct{}
+	stopOnce    sync.Once
 }
 
 type StreamMetricsData struct {
@@ -29,16 +50,68 @@ type StreamMetricsData struct {
 	OutputBytes         float64
 	OutputSkippedFrames float64
 	OutputFrames        float64
-	Error               error
+	OutputCongestion    float64
+	OutputReconnecting  bool
+	// OutputReconnects counts the reconnects that started within the window
+	OutputReconnects int
+	// OutputDuration is the latest duration of the stream in seconds
+	OutputDuration float64
+	// Elapsed is the time the deltas cover, 0 when there are no deltas yet
+	Elapsed time.Duration
+	Error   error
 }
 
-func NewStreamMetrics(client *goobs.Client, interval time.Duration) (*StreamMetrics, error) {
+func NewStreamMetrics(client *ObsClient, interval time.Duration) (*StreamMetrics, error) {
 	return &StreamMetrics{
 		client:   client,
 		interval: interval,
+		done:     make(chan struct{}),
 	}, nil
 }
 
+func (s *StreamMetrics) Name() string {
+	return "stream"
+}
+
+func (s *StreamMetrics) Describe() []Descriptor {
+	return []Descriptor{streamActiveColumn, outputBytesColumn, outputSkippedFramesColumn, outputFramesColumn, outputKbpsColumn, outputFpsColumn,
+		outputCongestionColumn, outputReconnectingColumn, outputReconnectsColumn, outputDurationColumn}
+}
+
+// Collect returns the stream state and the counter deltas, a stopped collector has no samples
+func (s *StreamMetrics) Collect() ([]Sample, error) {
+	if s.stopped() {
+		return nil, nil
+	}
+
+	data := s.GetAndResetMaxValues()
+	samples := []Sample{
+		streamActiveColumn.BoolSample(data.Active),
+		outputBytesColumn.Sample(data.OutputBytes),
+		outputSkippedFramesColumn.Sample(data.OutputSkippedFrames),
+		outputFramesColumn.Sample(data.OutputFrames),
+	}
+
+	// The rates use the actual time between collects, so they don't depend on the writer interval or its jitter.
+	// They are missing until there are deltas.
+	if data.Elapsed > 0 {
+		seconds := data.Elapsed.Seconds()
+		samples = append(samples,
+			outputKbpsColumn.Sample(data.OutputBytes*8/1000/seconds),
+			outputFpsColumn.Sample(data.OutputFrames/seconds),
+		)
+	} else {
+		samples = append(samples, Sample{Descriptor: outputKbpsColumn}, Sample{Descriptor: outputFpsColumn})
+	}
+	samples = append(samples,
+		outputCongestionColumn.Sample(data.OutputCongestion),
+		outputReconnectingColumn.BoolSample(data.OutputReconnecting),
+		outputReconnectsColumn.Sample(float64(data.OutputReconnects)),
+		outputDurationColumn.Sample(data.OutputDuration),
+	)
+	return samples, data.Error
+}
+
 func (s *StreamMetrics) GetAndResetMaxValues() StreamMetricsData {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -47,6 +120,19 @@ func (s *StreamMetrics) GetAndResetMaxValues() StreamMetricsData {
 	maxSkipped := s.maxSkippedFrames
 	active := s.lastActive
 	err := s.lastError
+	congestion := s.maxCongestion
+	reconnecting := s.reconnecting
+	reconnects := s.reconnects
+	s.maxCongestion = 0
+	s.reconnecting = false
+	s.reconnects = 0
+
+	now := time.Now()
+	var elapsed time.Duration
+	if !s.lastCollect.IsZero() {
+		elapsed = now.Sub(s.lastCollect)
+	}
+	s.lastCollect = now
 
 	if s.measurementCount < 2 {
 		maxTotalFrames := s.maxTotalFrames
@@ -58,11 +144,15 @@ func (s *StreamMetrics) GetAndResetMaxValues() StreamMetricsData {
 		s.maxTotalFrames = 0
 		s.lastError = nil
 		return StreamMetricsData{
-			Timestamp:           time.Now(),
+			Timestamp:           now,
 			Active:              active,
 			OutputBytes:         0,
 			OutputSkippedFrames: 0,
 			OutputFrames:        0,
+			OutputCongestion:    congestion,
+			OutputReconnecting:  reconnecting,
+			OutputReconnects:    reconnects,
+			OutputDuration:      s.duration,
 			Error:               err,
 		}
 	}
@@ -77,11 +167,16 @@ func (s *StreamMetrics) GetAndResetMaxValues() StreamMetricsData {
 	s.lastError = nil
 
 	return StreamMetricsData{
-		Timestamp:           time.Now(),
+		Timestamp:           now,
 		Active:              active,
 		OutputBytes:         bytesDelta,
 		OutputSkippedFrames: skippedDelta,
 		OutputFrames:        framesDelta,
+		OutputCongestion:    congestion,
+		OutputReconnecting:  reconnecting,
+		OutputReconnects:    reconnects,
+		OutputDuration:      s.duration,
+		Elapsed:             elapsed,
 		Error:               err,
 	}
 }
@@ -103,27 +198,68 @@ func (s *StreamMetrics) updateMetrics(outputActive bool, outputBytes, skippedFra
 	s.measurementCount++
 }
 
+// updateHealth records the health of the stream output, the duration is in milliseconds like OBS reports it
+func (s *StreamMetrics) updateHealth(congestion float64, reconnecting bool, durationMs float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if congestion > s.maxCongestion {
+		s.maxCongestion = congestion
+	}
+	if reconnecting {
+		if !s.lastReconnecting {
+			s.reconnects++
+		}
+		s.reconnecting = true
+	}
+	s.lastReconnecting = reconnecting
+	s.duration = durationMs / 1000
+}
+
 func (s *StreamMetrics) recordError(err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.lastError = err
 }
 
-func (s *StreamMetrics) Start() error {
+func (s *StreamMetrics) Start(ctx context.Context) error {
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		s.client.Lock()
 		status, err := s.client.Stream.GetStreamStatus()
+		s.client.Unlock()
 
 		if err != nil {
+			// The error is reported in the errors of the row
 			s.recordError(err)
-			fmt.Printf("Error getting stream status: %v\n", err)
 			continue
 		}
 
 		s.updateMetrics(status.OutputActive, status.OutputBytes, status.OutputSkippedFrames, status.OutputTotalFrames)
+		s.updateHealth(status.OutputCongestion, status.OutputReconnecting, status.OutputDuration)
 	}
+}
 
-	return nil
+// Stop ends the collection loop started by Start, e.g. when the OBS connection is lost
+func (s *StreamMetrics) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *StreamMetrics) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
 }
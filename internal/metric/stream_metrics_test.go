@@ -354,3 +354,104 @@ func TestStreamMetrics_RecordError(t *testing.T) {
 		t.Error("Expected error to be returned in GetAndResetMaxValues")
 	}
 }
+
+func TestStreamMetrics_Collect_StoppedHasNoSamples(t *testing.T) {
+	sm := &StreamMetrics{
+		maxOutputBytes:   1000.0,
+		measurementCount: 2,
+		done:             make(chan struct{}),
+	}
+
+	samples, err := sm.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if len(samples) != len(sm.Describe()) {
+		t.Errorf("Expected a sample per column, got %d", len(samples))
+	}
+
+	sm.Stop()
+	samples, err = sm.Collect()
+	if err != nil || len(samples) != 0 {
+		t.Errorf("Expected no samples from a stopped collector, got %v, %v", samples, err)
+	}
+}
+
+func TestStreamMetrics_Collect_Rates(t *testing.T) {
+	sm := &StreamMetrics{
+		prevOutputBytes:  1000.0,
+		prevTotalFrames:  100.0,
+		maxOutputBytes:   1000.0 + 750_000.0,
+		maxTotalFrames:   100.0 + 90.0,
+		measurementCount: 2,
+		lastCollect:      time.Now().Add(-1500 * time.Millisecond),
+		done:             make(chan struct{}),
+	}
+
+	samples, err := sm.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	// 750000 bytes and 90 frames in 1.5 seconds
+	values := sampleValues(samples)
+	if kbps := values["output_kbps"]; kbps < 3950 || kbps > 4000 {
+		t.Errorf("Expected about 4000 kbps, got %v", kbps)
+	}
+	if fps := values["output_fps"]; fps < 59 || fps > 60 {
+		t.Errorf("Expected about 60 fps, got %v", fps)
+	}
+}
+
+func TestStreamMetrics_Collect_NoRatesBeforeDeltas(t *testing.T) {
+	sm := &StreamMetrics{
+		maxOutputBytes:   1000.0,
+		measurementCount: 1,
+		lastCollect:      time.Now().Add(-time.Second),
+		done:             make(chan struct{}),
+	}
+
+	samples, _ := sm.Collect()
+	for _, s := range samples {
+		if (s.Name == "output_kbps" || s.Name == "output_fps") && s.Valid {
+			t.Errorf("Expected %s to be missing without deltas, got %v", s.Name, s.Value)
+		}
+	}
+}
+
+func TestStreamMetrics_Collect_Health(t *testing.T) {
+	sm := &StreamMetrics{done: make(chan struct{})}
+	sm.updateHealth(0.2, false, 60_000)
+	sm.updateHealth(0.7, true, 61_000)
+	sm.updateHealth(0.4, false, 62_000)
+	sm.updateHealth(0.5, true, 63_000)
+
+	samples, _ := sm.Collect()
+	values := sampleValues(samples)
+	if values["output_congestion"] != 0.7 {
+		t.Errorf("Expected the highest congestion 0.7, got %v", values["output_congestion"])
+	}
+	if values["output_reconnecting"] != 1 || values["output_reconnects"] != 2 {
+		t.Errorf("Expected 2 reconnects, got %v and %v", values["output_reconnecting"], values["output_reconnects"])
+	}
+	if values["output_duration"] != 63 {
+		t.Errorf("Expected the latest duration of 63s, got %v", values["output_duration"])
+	}
+
+	// A reconnect that continues into the next window is not counted again
+	sm.updateHealth(0, true, 64_000)
+	samples, _ = sm.Collect()
+	values = sampleValues(samples)
+	if values["output_reconnecting"] != 1 || values["output_reconnects"] != 0 {
+		t.Errorf("Expected a continued reconnect, got %v and %v", values["output_reconnecting"], values["output_reconnects"])
+	}
+	if values["output_congestion"] != 0 {
+		t.Errorf("Expected the congestion to be reset, got %v", values["output_congestion"])
+	}
+
+	sm.updateHealth(0, false, 65_000)
+	samples, _ = sm.Collect()
+	if values := sampleValues(samples); values["output_reconnecting"] != 0 {
+		t.Errorf("Expected the reconnect to be over, got %v", values["output_reconnecting"])
+	}
+}
@@ -1,6 +1,7 @@
 package metric
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -8,33 +9,62 @@ import (
 	"github.com/shirou/gopsutil/v4/mem"
 )
 
+var (
+	systemCpuUsageColumn    = Descriptor{Name: "system_cpu_percent", Help: "Overall system CPU usage.", Unit: "percent", Kind: Gauge, Precision: 2}
+	systemMemoryUsageColumn = Descriptor{Name: "system_memory_percent", Help: "Overall system memory usage.", Unit: "percent", Kind: Gauge, Precision: 2}
+)
+
 type SystemMetrics struct {
 	maxCpuUsage    float64
 	maxMemoryUsage float64
+	cpuUsages      Window
 	lastError      error
 	mu             sync.Mutex
 	interval       time.Duration
+	aggregations   []Aggregation
 }
 
 type SystemMetricsData struct {
 	Timestamp   time.Time
 	CpuUsage    float64
 	MemoryUsage float64
-	Error       error
+	// CpuUsages contains every CPU usage measured within the window
+	CpuUsages []float64
+	Error     error
 }
 
-func NewSystemMetrics(interval time.Duration) (*SystemMetrics, error) {
+// NewSystemMetrics creates the system metrics collector, the aggregations of the CPU usage are added as extra columns
+func NewSystemMetrics(interval time.Duration, aggregations []Aggregation) (*SystemMetrics, error) {
 	return &SystemMetrics{
-		interval: interval,
+		interval:     interval,
+		aggregations: aggregations,
 	}, nil
 }
 
+func (s *SystemMetrics) Name() string {
+	return "system"
+}
+
+func (s *SystemMetrics) Describe() []Descriptor {
+	columns := []Descriptor{systemCpuUsageColumn}
+	columns = append(columns, aggregatedColumns(systemCpuUsageColumn, s.aggregations)...)
+	return append(columns, systemMemoryUsageColumn)
+}
+
+func (s *SystemMetrics) Collect() ([]Sample, error) {
+	data := s.GetAndResetMaxValues()
+	samples := []Sample{systemCpuUsageColumn.Sample(data.CpuUsage)}
+	samples = append(samples, aggregatedSamples(systemCpuUsageColumn, s.aggregations, data.CpuUsages)...)
+	return append(samples, systemMemoryUsageColumn.Sample(data.MemoryUsage)), data.Error
+}
+
 func (s *SystemMetrics) GetAndResetMaxValues() SystemMetricsData {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	maxCpu := s.maxCpuUsage
 	maxMemory := s.maxMemoryUsage
+	cpuUsages := s.cpuUsages.Take()
 	err := s.lastError
 
 	s.maxCpuUsage = 0
@@ -45,6 +75,7 @@ func (s *SystemMetrics) GetAndResetMaxValues() SystemMetricsData {
 		Timestamp:   time.Now(),
 		CpuUsage:    maxCpu,
 		MemoryUsage: maxMemory,
+		CpuUsages:   cpuUsages,
 		Error:       err,
 	}
 }
@@ -53,6 +84,7 @@ func (s *SystemMetrics) updateMetrics(cpuUsage, memUsage float64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.cpuUsages.Add(cpuUsage)
 	if cpuUsage > s.maxCpuUsage {
 		s.maxCpuUsage = cpuUsage
 	}
@@ -67,11 +99,17 @@ func (s *SystemMetrics) recordError(err error) {
 	s.lastError = err
 }
 
-func (s *SystemMetrics) Start() error {
+func (s *SystemMetrics) Start(ctx context.Context) error {
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
 		cpuUsage, err := s.getCpuUsage()
 		if err != nil {
 			s.recordError(err)
@@ -86,8 +124,6 @@ func (s *SystemMetrics) Start() error {
 
 		s.updateMetrics(cpuUsage, memUsage)
 	}
-
-	return nil
 }
 
 func (s *SystemMetrics) getCpuUsage() (float64, error) {
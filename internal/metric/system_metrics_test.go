@@ -1,6 +1,7 @@
 package metric
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -123,7 +124,7 @@ func TestSystemMetrics_GetAndResetMaxValues_TracksMaximum(t *testing.T) {
 func TestSystemMetrics_NewSystemMetrics(t *testing.T) {
 	interval := 100 * time.Millisecond
 
-	sm, err := NewSystemMetrics(interval)
+	sm, err := NewSystemMetrics(interval, nil)
 
 	if err != nil {
 		t.Fatalf("NewSystemMetrics returned error: %v", err)
@@ -302,3 +303,28 @@ func TestSystemMetrics_RecordError(t *testing.T) {
 		t.Error("Expected error to be returned in GetAndResetMaxValues")
 	}
 }
+
+func TestSystemMetrics_Start_ReturnsWhenContextDone(t *testing.T) {
+	sm, err := NewSystemMetrics(10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("NewSystemMetrics failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- sm.Start(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Start to return without error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after the context was cancelled")
+	}
+}
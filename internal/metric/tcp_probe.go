@@ -0,0 +1,183 @@
+package metric
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// tcpProbeTimeout is how long a TCP handshake may take before the probe counts as failed
+const tcpProbeTimeout = 2 * time.Second
+
+// TCPProbe measures the TCP handshake time to an address, for targets that drop ICMP
+type TCPProbe struct {
+	name    string
+	address string
+	// resolved is the address with the IP of the host, it is only used by the goroutine that probes
+	resolved     string
+	maxConnect   time.Duration
+	connects     Window
+	attempts     int
+	failures     int
+	lastError    error
+	mu           sync.Mutex
+	interval     time.Duration
+	aggregations []Aggregation
+}
+
+// NewTCPProbe creates a TCP probe for address (host:port), name is used as prefix for its columns, e.g. obs_tcp_connect_ms.
+// The aggregations of the connect times within a writer interval are added as extra columns.
+func NewTCPProbe(name, address string, interval time.Duration, aggregations []Aggregation) (*TCPProbe, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid TCP probe address %q: %w", address, err)
+	}
+
+	return &TCPProbe{
+		name:         name,
+		address:      address,
+		interval:     interval,
+		aggregations: aggregations,
+	}, nil
+}
+
+// NewTCPProbePlaceholder declares the columns of a TCP probe whose address is not known yet
+func NewTCPProbePlaceholder(name string, aggregations []Aggregation) *Placeholder {
+	return NewPlaceholder(&TCPProbe{name: name, aggregations: aggregations})
+}
+
+func (p *TCPProbe) Name() string {
+	return p.name + "_tcp"
+}
+
+func (p *TCPProbe) Describe() []Descriptor {
+	columns := append([]Descriptor{p.connectColumn()}, aggregatedColumns(p.connectColumn(), p.aggregations)...)
+	return append(columns, p.failureColumn())
+}
+
+func (p *TCPProbe) connectColumn() Descriptor {
+	return p.column("tcp_connect_ms", "Maximum TCP handshake time within the writer interval.", "ms")
+}
+
+func (p *TCPProbe) failureColumn() Descriptor {
+	return p.column("tcp_failure_pct", "Percentage of TCP handshakes that failed within the writer interval.", "percent")
+}
+
+func (p *TCPProbe) column(family, help, unit string) Descriptor {
+	return Descriptor{
+		Name:      p.name + "_" + family,
+		Help:      help,
+		Unit:      unit,
+		Kind:      Gauge,
+		Precision: 2,
+		Family:    family,
+		Labels:    map[string]string{"target": p.name},
+	}
+}
+
+// Collect returns the handshake statistics of the window, the error is the last failed handshake
+func (p *TCPProbe) Collect() ([]Sample, error) {
+	p.mu.Lock()
+	maxConnect := p.maxConnect
+	connects := p.connects.Take()
+	attempts := p.attempts
+	failures := p.failures
+	err := p.lastError
+
+	p.maxConnect = 0
+	p.attempts = 0
+	p.failures = 0
+	p.lastError = nil
+	p.mu.Unlock()
+
+	samples := aggregatedSamples(p.connectColumn(), p.aggregations, connects)
+	if len(connects) > 0 {
+		samples = append(samples, p.connectColumn().Sample(durationMs(maxConnect)))
+	}
+	if attempts > 0 {
+		samples = append(samples, p.failureColumn().Sample(float64(failures)/float64(attempts)*100))
+	}
+	return samples, err
+}
+
+// Start probes the address every interval until ctx is done
+func (p *TCPProbe) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		connect, err := p.probe(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		p.record(connect, err)
+	}
+}
+
+// probe opens a TCP connection to the address and closes it as soon as the handshake is done.
+// The host is resolved once and again after a failed handshake, so the DNS lookup is not part of the handshake time.
+func (p *TCPProbe) probe(ctx context.Context) (time.Duration, error) {
+	if p.resolved == "" {
+		resolved, err := p.resolve(ctx)
+		if err != nil {
+			return 0, err
+		}
+		p.resolved = resolved
+	}
+
+	dialer := net.Dialer{Timeout: tcpProbeTimeout}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", p.resolved)
+	if err != nil {
+		p.resolved = ""
+		return 0, err
+	}
+	connect := time.Since(start)
+	conn.Close()
+
+	return connect, nil
+}
+
+// resolve returns the address with the first IP of its host
+func (p *TCPProbe) resolve(ctx context.Context) (string, error) {
+	host, port, err := net.SplitHostPort(p.address)
+	if err != nil {
+		return "", fmt.Errorf("invalid TCP probe address %q: %w", p.address, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tcpProbeTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("failed to resolve %s: no addresses", host)
+	}
+	return net.JoinHostPort(ips[0].IP.String(), port), nil
+}
+
+func (p *TCPProbe) record(connect time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+	if err != nil {
+		p.failures++
+		p.lastError = err
+		return
+	}
+
+	p.connects.Add(durationMs(connect))
+	if connect > p.maxConnect {
+		p.maxConnect = connect
+	}
+}
@@ -0,0 +1,151 @@
+package metric
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewTCPProbe_InvalidAddress(t *testing.T) {
+	if _, err := NewTCPProbe("obs", "example.com", time.Second, nil); err == nil {
+		t.Error("Expected an error for an address without port")
+	}
+}
+
+func TestTCPProbe_Collect_Listener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	p, err := NewTCPProbe("obs", listener.Addr().String(), 10*time.Millisecond, []Aggregation{AggregateCount})
+	if err != nil {
+		t.Fatalf("NewTCPProbe failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Start(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	samples, err := p.Collect()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	values := sampleValues(samples)
+	if values["obs_tcp_connect_ms_count"] < 1 {
+		t.Errorf("Expected at least one handshake, got %v", values["obs_tcp_connect_ms_count"])
+	}
+	if _, ok := values["obs_tcp_connect_ms"]; !ok {
+		t.Error("Expected obs_tcp_connect_ms sample")
+	}
+	if values["obs_tcp_failure_pct"] != 0 {
+		t.Errorf("Expected no failures, got %v%%", values["obs_tcp_failure_pct"])
+	}
+}
+
+func TestTCPProbe_Probe_ResolvesOnce(t *testing.T) {
+	// Listen on all addresses, localhost can resolve to an IPv4 or IPv6 address
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	p, err := NewTCPProbe("obs", net.JoinHostPort("localhost", port), time.Second, nil)
+	if err != nil {
+		t.Fatalf("NewTCPProbe failed: %v", err)
+	}
+
+	if _, err := p.probe(context.Background()); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	host, _, _ := net.SplitHostPort(p.resolved)
+	if net.ParseIP(host) == nil {
+		t.Errorf("Expected the handshake to use the resolved IP, got %q", p.resolved)
+	}
+
+	// A failed handshake resolves the host again on the next probe
+	listener.Close()
+	if _, err := p.probe(context.Background()); err == nil {
+		t.Fatal("Expected the handshake with a closed port to fail")
+	}
+	if p.resolved != "" {
+		t.Errorf("Expected the resolved address to be cleared, got %q", p.resolved)
+	}
+}
+
+func TestTCPProbe_Collect_ClosedPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	p, err := NewTCPProbe("obs", address, time.Second, nil)
+	if err != nil {
+		t.Fatalf("NewTCPProbe failed: %v", err)
+	}
+
+	p.record(p.probe(context.Background()))
+
+	samples, err := p.Collect()
+	if err == nil {
+		t.Error("Expected the failed handshake as error")
+	}
+
+	values := sampleValues(samples)
+	if _, ok := values["obs_tcp_connect_ms"]; ok {
+		t.Error("Expected no connect time without a successful handshake")
+	}
+	if values["obs_tcp_failure_pct"] != 100 {
+		t.Errorf("Expected 100%% failures, got %v", values["obs_tcp_failure_pct"])
+	}
+}
+
+func TestTCPProbe_Collect_ResetsWindow(t *testing.T) {
+	p := &TCPProbe{name: "obs"}
+	p.record(30*time.Millisecond, nil)
+	p.record(10*time.Millisecond, nil)
+
+	values := sampleValues(mustCollect(t, p))
+	if values["obs_tcp_connect_ms"] != 30 {
+		t.Errorf("Expected max connect time 30ms, got %v", values["obs_tcp_connect_ms"])
+	}
+
+	samples := mustCollect(t, p)
+	if len(samples) != 0 {
+		t.Errorf("Expected an empty window after Collect, got %v", samples)
+	}
+}
+
+func mustCollect(t *testing.T, c Collector) []Sample {
+	t.Helper()
+	samples, err := c.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	return samples
+}
@@ -0,0 +1,34 @@
+package monitor
+
+import (
+	"context"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
+
+var obsConnectedColumn = metric.Descriptor{
+	Name: "obs_connected",
+	Help: "Whether OBS Monitor is connected to OBS.",
+	Kind: metric.Bool,
+}
+
+// connectionCollector reports whether the monitor is connected to OBS when the row is written
+type connectionCollector struct {
+	connected func() bool
+}
+
+func (c *connectionCollector) Name() string {
+	return "connection"
+}
+
+func (c *connectionCollector) Describe() []metric.Descriptor {
+	return []metric.Descriptor{obsConnectedColumn}
+}
+
+func (c *connectionCollector) Start(ctx context.Context) error {
+	return nil
+}
+
+func (c *connectionCollector) Collect() ([]metric.Sample, error) {
+	return []metric.Sample{obsConnectedColumn.BoolSample(c.connected())}, nil
+}
@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andreykaipov/goobs/api/events"
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
+)
+
+// newEvent converts an OBS event into an event of the timeline, ok is false for events that are not followed
+func newEvent(instance string, event any, at time.Time) (e writer.Event, ok bool) {
+	e = writer.Event{Timestamp: at, Instance: instance}
+
+	switch ev := event.(type) {
+	case *events.StreamStateChanged:
+		e.Type = "StreamStateChanged"
+		e.Message = "stream " + outputState(ev.OutputState)
+		e.Data = map[string]any{"outputActive": ev.OutputActive, "outputState": ev.OutputState}
+	case *events.RecordStateChanged:
+		e.Type = "RecordStateChanged"
+		e.Message = "recording " + outputState(ev.OutputState)
+		e.Data = map[string]any{"outputActive": ev.OutputActive, "outputState": ev.OutputState, "outputPath": ev.OutputPath}
+	case *events.ReplayBufferStateChanged:
+		e.Type = "ReplayBufferStateChanged"
+		e.Message = "replay buffer " + outputState(ev.OutputState)
+		e.Data = map[string]any{"outputActive": ev.OutputActive, "outputState": ev.OutputState}
+	case *events.CurrentProgramSceneChanged:
+		e.Type = "CurrentProgramSceneChanged"
+		e.Message = "scene " + ev.SceneName
+		e.Data = map[string]any{"sceneName": ev.SceneName}
+	case *events.InputMuteStateChanged:
+		e.Type = "InputMuteStateChanged"
+		e.Message = ev.InputName + " unmuted"
+		if ev.InputMuted {
+			e.Message = ev.InputName + " muted"
+		}
+		e.Data = map[string]any{"inputName": ev.InputName, "inputMuted": ev.InputMuted}
+	case *events.CurrentProfileChanged:
+		e.Type = "CurrentProfileChanged"
+		e.Message = "profile " + ev.ProfileName
+		e.Data = map[string]any{"profileName": ev.ProfileName}
+	case *events.CurrentSceneCollectionChanged:
+		e.Type = "CurrentSceneCollectionChanged"
+		e.Message = "scene collection " + ev.SceneCollectionName
+		e.Data = map[string]any{"sceneCollectionName": ev.SceneCollectionName}
+	case *events.VendorEvent:
+		e.Type = "VendorEvent"
+		e.Message = fmt.Sprintf("%s %s", ev.VendorName, ev.EventType)
+		e.Data = map[string]any{"vendorName": ev.VendorName, "eventType": ev.EventType, "eventData": ev.EventData}
+	default:
+		return e, false
+	}
+	return e, true
+}
+
+// alertEvent adds an alert of a rule to the timeline
+func alertEvent(a alert.Alert) writer.Event {
+	return writer.Event{
+		Timestamp: a.Timestamp,
+		Instance:  a.Instance,
+		Type:      "Alert",
+		Message:   "alert " + a.Message(),
+		Data: map[string]any{
+			"rule":      a.Rule,
+			"metric":    a.Metric,
+			"value":     a.Value,
+			"threshold": a.Threshold,
+			"severity":  a.Severity,
+			"state":     string(a.State),
+		},
+	}
+}
+
+// outputState turns the state of an output into a word, e.g. OBS_WEBSOCKET_OUTPUT_STARTED into started
+func outputState(state string) string {
+	return strings.ToLower(strings.TrimPrefix(state, "OBS_WEBSOCKET_OUTPUT_"))
+}
@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreykaipov/goobs/api/events"
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
+)
+
+func TestNewEvent(t *testing.T) {
+	at := time.Date(2025, 12, 23, 15, 1, 25, 0, time.UTC)
+
+	tests := []struct {
+		event   any
+		typ     string
+		message string
+	}{
+		{&events.StreamStateChanged{OutputActive: true, OutputState: "OBS_WEBSOCKET_OUTPUT_STARTED"}, "StreamStateChanged", "stream started"},
+		{&events.StreamStateChanged{OutputState: "OBS_WEBSOCKET_OUTPUT_RECONNECTING"}, "StreamStateChanged", "stream reconnecting"},
+		{&events.RecordStateChanged{OutputState: "OBS_WEBSOCKET_OUTPUT_PAUSED"}, "RecordStateChanged", "recording paused"},
+		{&events.ReplayBufferStateChanged{OutputState: "OBS_WEBSOCKET_OUTPUT_STOPPED"}, "ReplayBufferStateChanged", "replay buffer stopped"},
+		{&events.CurrentProgramSceneChanged{SceneName: "BRB"}, "CurrentProgramSceneChanged", "scene BRB"},
+		{&events.InputMuteStateChanged{InputName: "Mic/Aux", InputMuted: true}, "InputMuteStateChanged", "Mic/Aux muted"},
+		{&events.InputMuteStateChanged{InputName: "Mic/Aux"}, "InputMuteStateChanged", "Mic/Aux unmuted"},
+		{&events.CurrentProfileChanged{ProfileName: "Twitch"}, "CurrentProfileChanged", "profile Twitch"},
+		{&events.CurrentSceneCollectionChanged{SceneCollectionName: "Show"}, "CurrentSceneCollectionChanged", "scene collection Show"},
+		{&events.VendorEvent{VendorName: "aitum-multistream", EventType: "output_started"}, "VendorEvent", "aitum-multistream output_started"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			e, ok := newEvent("backup", tt.event, at)
+			if !ok {
+				t.Fatalf("Expected %T to be followed", tt.event)
+			}
+			if e.Type != tt.typ || e.Message != tt.message {
+				t.Errorf("Expected %s %q, got %s %q", tt.typ, tt.message, e.Type, e.Message)
+			}
+			if e.Instance != "backup" || !e.Timestamp.Equal(at) {
+				t.Errorf("Expected the instance and time of the event, got %+v", e)
+			}
+		})
+	}
+}
+
+func TestNewEvent_Data(t *testing.T) {
+	e, _ := newEvent("", &events.InputMuteStateChanged{InputName: "Mic/Aux"}, time.Now())
+
+	// The JSON of goobs omits false values, the event log keeps them
+	if muted, ok := e.Data["inputMuted"]; !ok || muted != false {
+		t.Errorf("Expected inputMuted false in the data, got %v", e.Data)
+	}
+}
+
+func TestNewEvent_Ignored(t *testing.T) {
+	if _, ok := newEvent("", &events.InputVolumeMeters{}, time.Now()); ok {
+		t.Error("Expected volume meters to be ignored")
+	}
+}
+
+func TestAlertEvent(t *testing.T) {
+	e := alertEvent(alert.Alert{Instance: "backup", Rule: "high-cpu", Metric: "obs_cpu_percent", Operator: ">", Threshold: 90, Value: 95, Severity: "critical", State: alert.Firing})
+
+	if e.Type != "Alert" || e.Message != "alert high-cpu firing: obs_cpu_percent 95 > 90" || e.Instance != "backup" {
+		t.Errorf("Unexpected alert event %+v", e)
+	}
+	if e.Data["severity"] != "critical" || e.Data["state"] != "firing" {
+		t.Errorf("Expected the severity and state in the data, got %v", e.Data)
+	}
+}
@@ -0,0 +1,707 @@
+package monitor
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andreykaipov/goobs"
+	"github.com/andreykaipov/goobs/api/events"
+	"github.com/andreykaipov/goobs/api/events/subscriptions"
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
+)
+
+// instanceLabel labels the columns of an OBS instance in outputs that keep labels, like Prometheus.
+// It is not called instance, as Prometheus uses that label for the scrape target.
+const instanceLabel = "obs_instance"
+
+// Instance is a named OBS instance, its rows are tagged with the name
+type Instance struct {
+	Name     string
+	Host     string
+	Password string
+}
+
+// instance is the connection to a single OBS instance together with the collectors that depend on it
+type instance struct {
+	monitor  *Monitor
+	name     string
+	host     string
+	password string
+	// registry holds the collectors of this instance, the monitor adds the shared collectors to every row
+	registry      *metric.Registry
+	logger        *slog.Logger
+	client        *metric.ObsClient
+	streamMetrics *metric.StreamMetrics
+	recordMetrics *metric.RecordMetrics
+	outputMetrics *metric.OutputMetrics
+	obsStats      *metric.ObsStats
+	// audioMetrics follows the audio levels, nil when no inputs are watched
+	audioMetrics *metric.AudioMetrics
+	// pinger pings the stream server, nil when it is only probed over TCP
+	pinger       *metric.Pinger
+	connected    bool
+	streamActive bool
+	// exiting is set when OBS announced that it exits, so the lost connection is not reported as a failure
+	exiting      bool
+	obsVersion   string
+	streamDomain string
+	// offline is set while OBS was not started yet since the monitor started, its columns are placeholders
+	offline bool
+	// events holds the OBS events since the previous row
+	events []writer.Event
+	// columns holds the columns the writers were created with and the new columns that were reported,
+	// it is only used by the goroutine that writes the rows
+	columns map[string]bool
+	mu      sync.Mutex
+}
+
+func newInstance(m *Monitor, name, host, password string) *instance {
+	logger := m.logger.With("host", host)
+	if name != "" {
+		logger = logger.With("instance", name)
+	}
+
+	return &instance{
+		monitor:  m,
+		name:     name,
+		host:     host,
+		password: password,
+		registry: metric.NewRegistry(),
+		logger:   logger,
+	}
+}
+
+// reportNewColumns warns once about the columns that appeared after the writers were created, e.g. of an output
+// that was added to OBS while monitoring. The CSV, session and console outputs have a fixed header, so they miss them.
+func (inst *instance) reportNewColumns(samples []metric.Sample) {
+	var names []string
+	for _, s := range samples {
+		if !inst.columns[s.Name] {
+			inst.columns[s.Name] = true
+			names = append(names, s.Name)
+		}
+	}
+	if len(names) > 0 {
+		inst.logger.Warn(fmt.Sprintf("New columns of %s are missing from the CSV, session and console outputs: %s", inst.label(), strings.Join(names, ", ")))
+	}
+}
+
+// label names the instance in messages
+func (inst *instance) label() string {
+	if inst.name == "" {
+		return "OBS"
+	}
+	return "OBS " + inst.name
+}
+
+// start connects to OBS and starts the collectors of the instance.
+// With Wait an instance whose OBS is not running starts offline, monitorConnection connects it once OBS is started.
+func (inst *instance) start() error {
+	if err := inst.connect(); err != nil {
+		if !inst.monitor.connectionInfo.Wait {
+			return fmt.Errorf("failed to connect to %s: %w", inst.label(), err)
+		}
+		inst.logger.Info(fmt.Sprintf("Waiting for %s to start", inst.label()), "error", err)
+		inst.startOffline()
+		return nil
+	}
+	return inst.initialize()
+}
+
+// initialize reads the OBS version and stream server of a new connection and starts the collectors of the instance
+func (inst *instance) initialize() error {
+	client := inst.currentClient()
+	version, err := client.General.GetVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get %s version: %w", inst.label(), err)
+	}
+
+	// Get OBS stream server domain
+	streamSettings, err := client.Config.GetStreamServiceSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get stream settings of %s: %w", inst.label(), err)
+	}
+
+	serverURL := streamSettings.StreamServiceSettings.Server
+	if serverURL == "" {
+		return fmt.Errorf("stream server URL not found in settings of %s", inst.label())
+	}
+
+	streamDomain, err := extractDomain(serverURL)
+	if err != nil {
+		return fmt.Errorf("failed to extract domain from URL: %w", err)
+	}
+
+	inst.mu.Lock()
+	inst.obsVersion = version.ObsVersion
+	inst.streamDomain = streamDomain
+	inst.offline = false
+	inst.mu.Unlock()
+
+	// The registration order is the column order of the outputs
+	inst.startCollector(&connectionCollector{connected: inst.isConnected})
+
+	if err := inst.initializeProbes(serverURL); err != nil {
+		return err
+	}
+
+	return inst.startObsCollectors()
+}
+
+// startOffline declares the columns of an instance whose OBS is not running yet, in the order of initialize.
+// The outputs of OBS are only known once it is connected, their columns are added then.
+func (inst *instance) startOffline() {
+	m := inst.monitor
+	inst.mu.Lock()
+	inst.offline = true
+	inst.mu.Unlock()
+
+	inst.registerCollector(&connectionCollector{connected: inst.isConnected})
+
+	switch probeMode(m.connectionInfo.Probe) {
+	case ProbeICMP:
+		inst.registerCollector(metric.NewPingerPlaceholder("obs", m.connectionInfo.Aggregations))
+	case ProbeTCP:
+		inst.registerCollector(metric.NewTCPProbePlaceholder("obs", m.connectionInfo.Aggregations))
+	case ProbeBoth:
+		inst.registerCollector(metric.NewTCPProbePlaceholder("obs", m.connectionInfo.Aggregations))
+		inst.registerCollector(metric.NewPingerPlaceholder("obs", m.connectionInfo.Aggregations))
+	}
+
+	// The collectors are only created to declare their columns, they are never started without a client
+	streamMetrics, _ := metric.NewStreamMetrics(nil, m.metricInterval)
+	recordMetrics, _ := metric.NewRecordMetrics(nil, m.metricInterval)
+	obsStats, _ := metric.NewObsStats(nil, m.metricInterval, m.connectionInfo.Aggregations)
+	inst.registerCollector(metric.NewPlaceholder(streamMetrics))
+	inst.registerCollector(metric.NewPlaceholder(recordMetrics))
+	inst.registerCollector(metric.NewPlaceholder(obsStats))
+	if audio := m.connectionInfo.Audio; len(audio.Inputs) > 0 {
+		if audioMetrics, err := metric.NewAudioMetrics(audio.Inputs, audio.SilenceThresholdDB, audio.SilenceDuration); err == nil {
+			inst.registerCollector(metric.NewPlaceholder(audioMetrics))
+		}
+	}
+}
+
+func (inst *instance) isOffline() bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.offline
+}
+
+// connect establishes a connection to OBS
+func (inst *instance) connect() error {
+	// goobs holds its request lock while waiting for a response, so a lost connection is only
+	// noticed once pending requests time out. It interprets the timeout as milliseconds.
+	options := []goobs.Option{
+		goobs.WithPassword(inst.password),
+		goobs.WithResponseTimeout(time.Duration(responseTimeout.Milliseconds())),
+	}
+	// The audio levels are a high-volume event, OBS only sends them when they are subscribed explicitly
+	if len(inst.monitor.connectionInfo.Audio.Inputs) > 0 {
+		options = append(options, goobs.WithEventSubscriptions(subscriptions.All|subscriptions.InputVolumeMeters))
+	}
+
+	client, err := goobs.New(inst.host, options...)
+	if err != nil {
+		return err
+	}
+
+	inst.mu.Lock()
+	inst.client = metric.NewObsClient(client)
+	inst.mu.Unlock()
+	return nil
+}
+
+func (inst *instance) currentClient() *metric.ObsClient {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.client
+}
+
+func (inst *instance) isConnected() bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.connected
+}
+
+func (inst *instance) isStreamActive() bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.streamActive
+}
+
+// setStreamActive follows the stream state, writers that follow the stream open or close their session on a change
+func (inst *instance) setStreamActive(active bool, at time.Time) {
+	inst.mu.Lock()
+	changed := inst.streamActive != active
+	inst.streamActive = active
+	inst.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	var err error
+	if active {
+		message := fmt.Sprintf("%s started streaming", inst.label())
+		inst.logger.Info(message)
+		inst.notifyState(alert.StreamStarted, message)
+		err = inst.monitor.writers.StreamStarted(inst.name, at)
+	} else {
+		message := fmt.Sprintf("%s stopped streaming", inst.label())
+		inst.logger.Info(message)
+		inst.notifyState(alert.StreamStopped, message)
+		err = inst.monitor.writers.StreamStopped(inst.name, at)
+	}
+	if err != nil {
+		inst.logger.Error("Failed to follow the stream state", "error", err)
+	}
+}
+
+// notifyState reports a change of the instance to the notifiers
+func (inst *instance) notifyState(change alert.Change, message string) {
+	inst.monitor.notifyState(alert.StateChange{
+		Timestamp: time.Now(),
+		Instance:  inst.name,
+		Change:    change,
+		Message:   message,
+	})
+}
+
+// recordStateChanged passes a change of the record output to the record metrics, which only poll its status
+func (inst *instance) recordStateChanged(state string) {
+	switch state {
+	case "OBS_WEBSOCKET_OUTPUT_STARTED":
+		inst.logger.Info(fmt.Sprintf("%s started recording", inst.label()))
+	case "OBS_WEBSOCKET_OUTPUT_STOPPED":
+		inst.logger.Info(fmt.Sprintf("%s stopped recording", inst.label()))
+	}
+
+	inst.mu.Lock()
+	recordMetrics := inst.recordMetrics
+	inst.mu.Unlock()
+	if recordMetrics != nil {
+		recordMetrics.StateChanged(state)
+	}
+}
+
+// volumeMeters passes the audio levels to the audio metrics
+func (inst *instance) volumeMeters(e *events.InputVolumeMeters) {
+	inst.mu.Lock()
+	audioMetrics := inst.audioMetrics
+	inst.mu.Unlock()
+	if audioMetrics != nil {
+		audioMetrics.VolumeMeters(e.Inputs)
+	}
+}
+
+// syncStreamState reads the stream state from OBS, for changes that happened while no events were received.
+// A stream that is already live started when its output started.
+func (inst *instance) syncStreamState() {
+	client := inst.currentClient()
+	client.Lock()
+	status, err := client.Stream.GetStreamStatus()
+	client.Unlock()
+	if err != nil {
+		inst.logger.Warn(fmt.Sprintf("Failed to get stream status of %s", inst.label()), "error", err)
+		return
+	}
+
+	at := time.Now()
+	if status.OutputActive {
+		at = at.Add(-time.Duration(status.OutputDuration) * time.Millisecond)
+	}
+	inst.setStreamActive(status.OutputActive, at)
+}
+
+// startCollector starts a collector of this instance, its columns are labeled with the instance name
+func (inst *instance) startCollector(c metric.Collector) {
+	inst.monitor.startCollector(inst.registry, inst.labeled(c))
+}
+
+// registerCollector adds a collector of this instance without starting it
+func (inst *instance) registerCollector(c metric.Collector) {
+	inst.registry.Register(inst.labeled(c))
+}
+
+func (inst *instance) labeled(c metric.Collector) metric.Collector {
+	if inst.name != "" {
+		return &labeledCollector{Collector: c, instance: inst.name}
+	}
+	return c
+}
+
+// initializeProbes starts the probes of the stream server according to the probe mode
+func (inst *instance) initializeProbes(serverURL string) error {
+	m := inst.monitor
+	probe := probeMode(m.connectionInfo.Probe)
+
+	if probe == ProbeTCP || probe == ProbeBoth {
+		address, ok, err := ingestAddress(serverURL)
+		if err != nil {
+			return fmt.Errorf("failed to extract ingest address from URL: %w", err)
+		}
+
+		if ok {
+			tcpProbe, err := metric.NewTCPProbe("obs", address, m.metricInterval, m.connectionInfo.Aggregations)
+			if err != nil {
+				return fmt.Errorf("failed to initialize OBS TCP probe: %w", err)
+			}
+			inst.startCollector(tcpProbe)
+			inst.logger.Info(fmt.Sprintf("Probing TCP %s every %v", address, m.metricInterval), "target", "obs")
+		} else if probe == ProbeTCP {
+			inst.logger.Info(fmt.Sprintf("Stream server %s does not use TCP, pinging it instead", serverURL))
+			probe = ProbeBoth
+		}
+	}
+
+	if probe == ProbeICMP || probe == ProbeBoth {
+		obsPinger, err := metric.NewPinger("obs", inst.streamDomain, m.metricInterval, m.connectionInfo.Aggregations)
+		if err != nil {
+			return fmt.Errorf("failed to initialize OBS pinger: %w", err)
+		}
+		inst.startCollector(obsPinger)
+		inst.pinger = obsPinger
+		inst.logger.Info(fmt.Sprintf("Pinging %s every %v", inst.streamDomain, m.metricInterval), "target", "obs")
+	}
+
+	return nil
+}
+
+// startObsCollectors creates the collectors that depend on the current OBS client and starts them.
+// They replace the collectors of a previous connection.
+func (inst *instance) startObsCollectors() error {
+	m := inst.monitor
+	client := inst.currentClient()
+
+	streamMetrics, err := metric.NewStreamMetrics(client, m.metricInterval)
+	if err != nil {
+		return fmt.Errorf("failed to initialize stream metrics: %w", err)
+	}
+
+	recordMetrics, err := metric.NewRecordMetrics(client, m.metricInterval)
+	if err != nil {
+		return fmt.Errorf("failed to initialize record metrics: %w", err)
+	}
+
+	outputMetrics, err := metric.NewOutputMetrics(client, m.metricInterval)
+	if err != nil {
+		return fmt.Errorf("failed to initialize output metrics: %w", err)
+	}
+
+	obsStats, err := metric.NewObsStats(client, m.metricInterval, m.connectionInfo.Aggregations)
+	if err != nil {
+		return fmt.Errorf("failed to initialize OBS stats: %w", err)
+	}
+
+	var audioMetrics *metric.AudioMetrics
+	if audio := m.connectionInfo.Audio; len(audio.Inputs) > 0 {
+		audioMetrics, err = metric.NewAudioMetrics(audio.Inputs, audio.SilenceThresholdDB, audio.SilenceDuration)
+		if err != nil {
+			return fmt.Errorf("failed to initialize audio metrics: %w", err)
+		}
+	}
+
+	inst.mu.Lock()
+	inst.streamMetrics = streamMetrics
+	inst.recordMetrics = recordMetrics
+	inst.outputMetrics = outputMetrics
+	inst.obsStats = obsStats
+	inst.audioMetrics = audioMetrics
+	inst.connected = true
+	inst.mu.Unlock()
+
+	inst.startCollector(streamMetrics)
+	inst.startCollector(recordMetrics)
+	inst.startCollector(outputMetrics)
+	inst.startCollector(obsStats)
+	if audioMetrics != nil {
+		inst.startCollector(audioMetrics)
+	}
+
+	return nil
+}
+
+// stopObsCollectors stops the collectors bound to a lost OBS client
+func (inst *instance) stopObsCollectors() {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.connected = false
+	if inst.streamMetrics != nil {
+		inst.streamMetrics.Stop()
+	}
+	if inst.recordMetrics != nil {
+		inst.recordMetrics.Stop()
+	}
+	if inst.outputMetrics != nil {
+		inst.outputMetrics.Stop()
+	}
+	if inst.obsStats != nil {
+		inst.obsStats.Stop()
+	}
+	if inst.audioMetrics != nil {
+		inst.audioMetrics.Stop()
+	}
+}
+
+// printInfo reports the versions of a connected instance, an offline instance reports them once it is started
+func (inst *instance) printInfo() {
+	if inst.isOffline() {
+		return
+	}
+	client := inst.currentClient()
+	client.Lock()
+	version, err := client.General.GetVersion()
+	client.Unlock()
+	if err != nil {
+		inst.logger.Warn(fmt.Sprintf("Failed to get %s version", inst.label()), "error", err)
+		return
+	}
+
+	if inst.monitor.connectionInfo.Logger != nil {
+		inst.logger.Info(fmt.Sprintf("Connected to %s", inst.label()),
+			"obs_version", version.ObsVersion,
+			"server_protocol_version", version.ObsWebSocketVersion,
+			"client_protocol_version", goobs.ProtocolVersion,
+			"client_library_version", goobs.LibraryVersion,
+		)
+		return
+	}
+
+	if inst.name != "" {
+		fmt.Printf("Instance: %s (%s)\n", inst.name, inst.host)
+	}
+	fmt.Printf("OBS Studio version: %s\n", version.ObsVersion)
+	fmt.Printf("Server protocol version: %s\n", version.ObsWebSocketVersion)
+	fmt.Printf("Client protocol version: %s\n", goobs.ProtocolVersion)
+	fmt.Printf("Client library version: %s\n\n", goobs.LibraryVersion)
+}
+
+func (inst *instance) disconnect() {
+	if client := inst.currentClient(); client != nil {
+		client.Disconnect()
+	}
+}
+
+// monitorConnection keeps the OBS connection alive, reconnecting whenever it is lost, until the monitor is shut down.
+// An instance that started offline is connected first.
+func (inst *instance) monitorConnection() {
+	if inst.isOffline() {
+		if inst.waitForOBS() != nil {
+			return
+		}
+		inst.printInfo()
+		inst.syncStreamState()
+	}
+
+	for {
+		inst.listen()
+		if inst.monitor.ctx.Err() != nil {
+			return
+		}
+
+		inst.stopObsCollectors()
+		inst.logger.Warn(fmt.Sprintf("%s connection lost, reconnecting...", inst.label()))
+
+		inst.mu.Lock()
+		message := fmt.Sprintf("%s connection lost", inst.label())
+		if inst.exiting {
+			message = fmt.Sprintf("%s exited", inst.label())
+		}
+		inst.exiting = false
+		inst.mu.Unlock()
+		inst.notifyState(alert.Disconnected, message)
+
+		if !inst.reconnect() {
+			return
+		}
+		message = fmt.Sprintf("Reconnected to %s", inst.label())
+		inst.logger.Info(message)
+		inst.notifyState(alert.Reconnected, message)
+		inst.monitor.summary.Reconnected(inst.name)
+		inst.syncStreamState()
+	}
+}
+
+// listen handles OBS events until the connection is lost or the monitor is shut down
+func (inst *instance) listen() {
+	ctx := inst.monitor.ctx
+	client := inst.currentClient()
+
+	listenDone := make(chan struct{})
+	go func() {
+		defer close(listenDone)
+		client.Listen(func(event any) {
+			inst.recordEvent(event)
+
+			switch e := event.(type) {
+			case *events.StreamStateChanged:
+				switch e.OutputState {
+				case "OBS_WEBSOCKET_OUTPUT_STARTED":
+					inst.setStreamActive(true, time.Now())
+				case "OBS_WEBSOCKET_OUTPUT_STOPPED":
+					inst.setStreamActive(false, time.Now())
+				}
+			case *events.RecordStateChanged:
+				inst.recordStateChanged(e.OutputState)
+			case *events.InputVolumeMeters:
+				inst.volumeMeters(e)
+			case *events.ExitStarted:
+				inst.logger.Warn(fmt.Sprintf("%s is exiting", inst.label()))
+				inst.mu.Lock()
+				inst.exiting = true
+				inst.mu.Unlock()
+				client.Disconnect()
+			}
+		})
+	}()
+
+	// goobs does not notice connections that drop without a close frame, so probe them
+	healthCheck := time.NewTicker(healthCheckInterval)
+	defer healthCheck.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			client.Disconnect()
+			<-listenDone
+			return
+		case <-listenDone:
+			return
+		case <-healthCheck.C:
+			client.Lock()
+			_, err := client.General.GetVersion()
+			client.Unlock()
+			if err != nil {
+				client.Disconnect()
+				<-listenDone
+				return
+			}
+		}
+	}
+}
+
+// recordEvent adds an OBS event to the next row and writes it to the writers that record events
+func (inst *instance) recordEvent(event any) {
+	e, ok := newEvent(inst.name, event, time.Now())
+	if !ok {
+		return
+	}
+
+	inst.mu.Lock()
+	inst.events = append(inst.events, e)
+	inst.mu.Unlock()
+
+	if err := inst.monitor.writers.WriteEvent(e); err != nil {
+		inst.logger.Error("Failed to write event", "error", err)
+	}
+}
+
+// takeEvents returns the OBS events since the previous call
+func (inst *instance) takeEvents() []writer.Event {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	taken := inst.events
+	inst.events = nil
+	return taken
+}
+
+// reconnect dials OBS with exponential backoff until it succeeds or the monitor is shut down
+func (inst *instance) reconnect() bool {
+	ctx := inst.monitor.ctx
+	delay := reconnectInitialDelay
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+
+		err := inst.connect()
+		if err == nil {
+			if err = inst.startObsCollectors(); err == nil {
+				return true
+			}
+			inst.currentClient().Disconnect()
+		}
+
+		delay = min(delay*2, reconnectMaxDelay)
+		inst.logger.Warn(fmt.Sprintf("Reconnecting to %s failed, retrying in %v", inst.label(), delay), "error", err)
+	}
+}
+
+// waitForOBS dials OBS with exponential backoff until it is started and the instance is initialized,
+// or the monitor is shut down
+func (inst *instance) waitForOBS() error {
+	ctx := inst.monitor.ctx
+	delay := reconnectInitialDelay
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, reconnectMaxDelay)
+		if err := inst.connect(); err != nil {
+			inst.logger.Debug(fmt.Sprintf("%s is not reachable, retrying in %v", inst.label(), delay), "error", err)
+			continue
+		}
+		if err := inst.initialize(); err != nil {
+			inst.currentClient().Disconnect()
+			inst.logger.Warn(fmt.Sprintf("Starting to monitor %s failed, retrying in %v", inst.label(), delay), "error", err)
+			continue
+		}
+
+		inst.logger.Info(fmt.Sprintf("%s started", inst.label()))
+		return nil
+	}
+}
+
+// labeledCollector labels the columns of a collector with the OBS instance it belongs to.
+// Its errors are reported as <instance>/<collector>.
+type labeledCollector struct {
+	metric.Collector
+	instance string
+}
+
+func (c *labeledCollector) Name() string {
+	return c.instance + "/" + c.Collector.Name()
+}
+
+func (c *labeledCollector) Describe() []metric.Descriptor {
+	columns := slices.Clone(c.Collector.Describe())
+	for i := range columns {
+		columns[i] = c.label(columns[i])
+	}
+	return columns
+}
+
+func (c *labeledCollector) Collect() ([]metric.Sample, error) {
+	samples, err := c.Collector.Collect()
+	for i := range samples {
+		samples[i].Descriptor = c.label(samples[i].Descriptor)
+	}
+	return samples, err
+}
+
+func (c *labeledCollector) label(d metric.Descriptor) metric.Descriptor {
+	labels := maps.Clone(d.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[instanceLabel] = c.instance
+	d.Labels = labels
+	return d
+}
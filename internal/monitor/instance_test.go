@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
+
+func TestLabeledCollector(t *testing.T) {
+	pinger, err := metric.NewPinger("obs", "example.com", 0, nil)
+	if err != nil {
+		t.Fatalf("NewPinger failed: %v", err)
+	}
+	c := &labeledCollector{Collector: pinger, instance: "backup"}
+
+	if c.Name() != "backup/obs_ping" {
+		t.Errorf("Expected name backup/obs_ping, got %s", c.Name())
+	}
+
+	for _, column := range c.Describe() {
+		if column.Labels[instanceLabel] != "backup" {
+			t.Errorf("Expected column %s to be labeled with the instance, got %v", column.Name, column.Labels)
+		}
+		if column.Labels["target"] != "obs" {
+			t.Errorf("Expected column %s to keep its target label, got %v", column.Name, column.Labels)
+		}
+	}
+
+	// The labels of the wrapped collector are not modified
+	for _, column := range pinger.Describe() {
+		if _, ok := column.Labels[instanceLabel]; ok {
+			t.Errorf("Expected wrapped column %s to be unlabeled, got %v", column.Name, column.Labels)
+		}
+	}
+}
+
+func TestLabeledCollector_RegistryErrors(t *testing.T) {
+	registry := metric.NewRegistry()
+	registry.Register(&labeledCollector{Collector: &failingCollector{}, instance: "main"})
+
+	_, errs := registry.Collect()
+	if len(errs) != 1 || errs[0].Collector != "main/failing" {
+		t.Errorf("Expected error of main/failing, got %v", errs)
+	}
+}
+
+type failingCollector struct{}
+
+func (c *failingCollector) Name() string {
+	return "failing"
+}
+
+func (c *failingCollector) Describe() []metric.Descriptor {
+	return nil
+}
+
+func (c *failingCollector) Start(ctx context.Context) error {
+	return nil
+}
+
+func (c *failingCollector) Collect() ([]metric.Sample, error) {
+	return nil, errors.New("failed")
+}
+
+func TestInstance_ReportNewColumns(t *testing.T) {
+	var logs bytes.Buffer
+	inst := &instance{
+		name:    "backup",
+		logger:  slog.New(slog.NewTextHandler(&logs, nil)),
+		columns: map[string]bool{"stream_active": true},
+	}
+
+	active := metric.Descriptor{Name: "stream_active", Kind: metric.Bool}
+	bytesColumn := metric.Descriptor{Name: "output_twitch_bytes", Kind: metric.Counter}
+	samples := []metric.Sample{active.BoolSample(true), bytesColumn.Sample(1000)}
+
+	inst.reportNewColumns(samples)
+	if !strings.Contains(logs.String(), "New columns of OBS backup are missing from the CSV, session and console outputs: output_twitch_bytes") {
+		t.Errorf("Expected a warning about the new column, got %q", logs.String())
+	}
+
+	// A column is reported once
+	logs.Reset()
+	inst.reportNewColumns(samples)
+	if logs.Len() != 0 {
+		t.Errorf("Expected no warning for a reported column, got %q", logs.String())
+	}
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// consoleHandler prints log messages the way they are shown in an interactive session: the message,
+// followed by the error when there is one. The other attributes are only kept by structured handlers.
+type consoleHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	attrs []slog.Attr
+}
+
+func newConsoleHandler(w io.Writer) *consoleHandler {
+	return &consoleHandler{w: w, mu: &sync.Mutex{}}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	message := record.Message
+	appendError := func(a slog.Attr) bool {
+		if a.Key == "error" {
+			message += ": " + a.Value.String()
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		appendError(a)
+	}
+	record.Attrs(appendError)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintln(h.w, message)
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &consoleHandler{w: h.w, mu: h.mu, attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	return h
+}
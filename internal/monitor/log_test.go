@@ -0,0 +1,22 @@
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newConsoleHandler(&buf))
+
+	logger.Info("Reconnected to OBS", "instance", "main")
+	logger.With("instance", "main").Warn("Reconnecting to OBS failed", "error", errors.New("connection refused"))
+	logger.Debug("Not shown")
+
+	expected := "Reconnected to OBS\nReconnecting to OBS failed: connection refused\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
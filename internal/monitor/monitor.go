@@ -2,209 +2,506 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/url"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/andreykaipov/goobs"
-	"github.com/andreykaipov/goobs/api/events"
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
 	"github.com/joepadmiraal/obs-monitor/internal/metric"
+	"github.com/joepadmiraal/obs-monitor/internal/summary"
+	"github.com/joepadmiraal/obs-monitor/internal/webhook"
 	"github.com/joepadmiraal/obs-monitor/internal/writer"
 )
 
+const (
+	reconnectInitialDelay = 500 * time.Millisecond
+	reconnectMaxDelay     = 30 * time.Second
+	healthCheckInterval   = 2 * time.Second
+	responseTimeout       = 2 * time.Second
+)
+
+// Probe modes for the stream server, TCP is meant for networks that drop ICMP
+const (
+	ProbeICMP = "icmp"
+	ProbeTCP  = "tcp"
+	ProbeBoth = "both"
+)
+
+// ProbeModes lists all probe modes
+var ProbeModes = []string{ProbeICMP, ProbeTCP, ProbeBoth}
+
+// probeMode returns the probe mode, ICMP when it is not set
+func probeMode(probe string) string {
+	if probe == "" {
+		return ProbeICMP
+	}
+	return probe
+}
+
+// DefaultPingTargets are pinged when no ping targets are configured
+var DefaultPingTargets = []PingTarget{{Name: "google", Host: "google.com"}}
+
+// validName restricts ping target and instance names to characters that are valid in column and Prometheus label names
+var validName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// PingTarget is a host that is pinged next to the stream server, its name is the prefix of its columns
+type PingTarget struct {
+	Name string
+	Host string
+}
+
+// ParsePingTarget parses a ping target in the form name=host
+func ParsePingTarget(value string) (PingTarget, error) {
+	name, host, ok := strings.Cut(value, "=")
+	if !ok {
+		return PingTarget{}, fmt.Errorf("invalid ping target %q, expected name=host", value)
+	}
+
+	target := PingTarget{Name: strings.TrimSpace(name), Host: strings.TrimSpace(host)}
+	if err := target.validate(); err != nil {
+		return PingTarget{}, err
+	}
+	return target, nil
+}
+
+func (t PingTarget) validate() error {
+	if !validName.MatchString(t.Name) {
+		return fmt.Errorf("invalid ping target name %q, expected lowercase letters, digits and underscores", t.Name)
+	}
+	if t.Name == "obs" {
+		return fmt.Errorf("ping target name obs is reserved for the stream server")
+	}
+	if t.Host == "" {
+		return fmt.Errorf("ping target %s has no host", t.Name)
+	}
+	return nil
+}
+
+// ValidatePingTargets checks every target and that the names are unique, it reports all problems at once
+func ValidatePingTargets(targets []PingTarget) error {
+	var errs []error
+	names := map[string]bool{}
+	for _, target := range targets {
+		if err := target.validate(); err != nil {
+			errs = append(errs, err)
+		}
+		if names[target.Name] {
+			errs = append(errs, fmt.Errorf("duplicate ping target name %s", target.Name))
+		}
+		names[target.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// Audio selects the OBS inputs whose audio levels are monitored
+type Audio struct {
+	// Inputs are the names of the OBS inputs, audio is not monitored when empty
+	Inputs []string
+	// SilenceThresholdDB is the peak level in dBFS below which an input is quiet
+	SilenceThresholdDB float64
+	// SilenceDuration is how long an input must be quiet before it is flagged as silent
+	SilenceDuration time.Duration
+}
+
+// ValidateAudio checks the inputs and the silence detection, it reports all problems at once
+func ValidateAudio(audio Audio) error {
+	var errs []error
+	if _, err := metric.NewAudioMetrics(audio.Inputs, audio.SilenceThresholdDB, audio.SilenceDuration); err != nil {
+		errs = append(errs, err)
+	}
+	if audio.SilenceThresholdDB > 0 {
+		errs = append(errs, fmt.Errorf("silence threshold must not be above 0 dBFS, got %v", audio.SilenceThresholdDB))
+	}
+	if audio.SilenceDuration < 0 {
+		errs = append(errs, fmt.Errorf("silence duration must not be negative, got %v", audio.SilenceDuration))
+	}
+	return errors.Join(errs...)
+}
+
 type ObsConnectionInfo struct {
-	Password       string
-	Host           string
-	CSVFile        string
-	MetricInterval int
-	WriterInterval int
+	Password         string
+	Host             string
+	CSVFile          string
+	Outputs          []string
+	PrometheusListen string
+	MetricInterval   int
+	WriterInterval   int
+	// Aggregations are added as extra columns for the RTTs and CPU usages
+	Aggregations []metric.Aggregation
+	// Probe selects how the stream server is probed, one of ProbeModes, ICMP when empty
+	Probe string
+	// PingTargets are pinged next to the stream server, DefaultPingTargets when empty
+	PingTargets []PingTarget
+	// Instances are the OBS instances to monitor, a single unnamed instance on Host when empty
+	Instances []Instance
+	// Wait keeps retrying to connect when OBS is not running yet, instead of failing to start
+	Wait bool
+	// Logger receives the status messages, they are printed to stdout when nil
+	Logger *slog.Logger
+	// SkipIdle drops the rows of instances that are not streaming from the console, CSV and JSONL outputs
+	SkipIdle bool
+	// Audio selects the inputs whose audio levels are monitored
+	Audio Audio
+	// Alerts are evaluated against every row, their alerts are logged and passed to the Notifiers
+	Alerts    []alert.Rule
+	Notifiers []alert.Notifier
+	// Webhooks receive the alerts and the stream and connection changes of every instance
+	Webhooks []webhook.Endpoint
 }
 
 type Monitor struct {
-	client         *goobs.Client
 	connectionInfo ObsConnectionInfo
-	obsPinger      *metric.Pinger
-	googlePinger   *metric.Pinger
-	streamMetrics  *metric.StreamMetrics
-	obsStats       *metric.ObsStats
-	systemMetrics  *metric.SystemMetrics
-	csvWriter      *writer.CSVWriter
-	consoleWriter  *writer.ConsoleWriter
+	instances      []*instance
+	// shared holds the collectors that do not depend on an OBS instance, their columns are part of every row
+	shared *metric.Registry
+	// pingers are the shared pingers, their RTTs are part of the summary of every instance
+	pingers        []*metric.Pinger
+	summary        *summary.Recorder
+	writers        *writer.MultiWriter
+	alerts         *alert.Engine
+	notifiers      []alert.Notifier
+	logger         *slog.Logger
 	metricInterval time.Duration
 	writerInterval time.Duration
 	ctx            context.Context
 	cancel         context.CancelFunc
-	shutdownDone   chan struct{}
+	// lastWrite is when the rows of a writer interval were last written, in Unix nanoseconds
+	lastWrite atomic.Int64
+	// goroutines tracks all goroutines started by the monitor, Done is closed once they have returned
+	goroutines   sync.WaitGroup
+	shutdownDone chan struct{}
 }
 
 // NewMonitor Connects to OBS and
 func NewMonitor(connectionInfo ObsConnectionInfo) (*Monitor, error) {
+	if err := ValidatePingTargets(connectionInfo.PingTargets); err != nil {
+		return nil, err
+	}
+	if err := ValidateInstances(connectionInfo.Instances); err != nil {
+		return nil, err
+	}
+	if err := ValidateAudio(connectionInfo.Audio); err != nil {
+		return nil, err
+	}
+
+	for _, endpoint := range connectionInfo.Webhooks {
+		if err := endpoint.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	alerts, err := alert.NewEngine(connectionInfo.Alerts, time.Duration(connectionInfo.WriterInterval)*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Monitor{
+	m := &Monitor{
 		connectionInfo: connectionInfo,
 		metricInterval: time.Duration(connectionInfo.MetricInterval) * time.Millisecond,
 		writerInterval: time.Duration(connectionInfo.WriterInterval) * time.Millisecond,
 		ctx:            ctx,
 		cancel:         cancel,
+		shared:         metric.NewRegistry(),
+		writers:        writer.NewMultiWriter(),
+		alerts:         alerts,
+		logger:         connectionInfo.Logger,
 		shutdownDone:   make(chan struct{}),
-	}, nil
-}
-
-// connect establishes a connection to OBS (internal use only)
-func (m *Monitor) connect() error {
-	var err error
-	m.client, err = goobs.New(m.connectionInfo.Host, goobs.WithPassword(m.connectionInfo.Password))
-	if err != nil {
-		return err
 	}
-	return nil
-}
-
-// Start connects to OBS and starts all monitoring components
-func (m *Monitor) Start() error {
-	// Connect to OBS
-	if err := m.connect(); err != nil {
-		return fmt.Errorf("failed to connect to OBS: %w", err)
+	if m.logger == nil {
+		m.logger = slog.New(newConsoleHandler(os.Stdout))
 	}
-
-	// Get OBS version
-	version, err := m.client.General.GetVersion()
-	if err != nil {
-		return fmt.Errorf("failed to get OBS version: %w", err)
+	m.notifiers = append([]alert.Notifier{alert.NewLogNotifier(m.logger)}, connectionInfo.Notifiers...)
+	for _, endpoint := range connectionInfo.Webhooks {
+		w, err := webhook.New(endpoint, m.logger)
+		if err != nil {
+			return nil, err
+		}
+		m.notifiers = append(m.notifiers, w)
 	}
 
-	// Get OBS stream server domain
-	streamSettings, err := m.client.Config.GetStreamServiceSettings()
-	if err != nil {
-		return fmt.Errorf("failed to get stream settings: %w", err)
+	if len(connectionInfo.Instances) == 0 {
+		m.instances = []*instance{newInstance(m, "", connectionInfo.Host, connectionInfo.Password)}
 	}
-
-	serverURL := streamSettings.StreamServiceSettings.Server
-	if serverURL == "" {
-		return fmt.Errorf("stream server URL not found in settings")
+	for _, inst := range connectionInfo.Instances {
+		m.instances = append(m.instances, newInstance(m, inst.Name, inst.Host, inst.Password))
 	}
 
-	streamDomain, err := extractDomain(serverURL)
-	if err != nil {
-		return fmt.Errorf("failed to extract domain from URL: %w", err)
-	}
+	return m, nil
+}
 
-	if err := m.initializePingers(streamDomain); err != nil {
-		return err
+// ValidateInstances checks that every instance has a valid, unique name and a host, it reports all problems at once
+func ValidateInstances(instances []Instance) error {
+	var errs []error
+	names := map[string]bool{}
+	for _, inst := range instances {
+		if !validName.MatchString(inst.Name) {
+			errs = append(errs, fmt.Errorf("invalid instance name %q, expected lowercase letters, digits and underscores", inst.Name))
+		}
+		if inst.Host == "" {
+			errs = append(errs, fmt.Errorf("instance %s has no host", inst.Name))
+		}
+		if names[inst.Name] {
+			errs = append(errs, fmt.Errorf("duplicate instance name %s", inst.Name))
+		}
+		names[inst.Name] = true
 	}
+	return errors.Join(errs...)
+}
 
-	// Initialize stream metrics
-	m.streamMetrics, err = metric.NewStreamMetrics(m.client, m.metricInterval)
-	if err != nil {
-		return fmt.Errorf("failed to initialize stream metrics: %w", err)
+// Start connects to all OBS instances and starts all monitoring components.
+// With Wait the instances whose OBS is not running yet are connected in the background, so they don't delay the others.
+// When it fails, everything that was already started is stopped again.
+func (m *Monitor) Start() (err error) {
+	defer func() {
+		if err != nil {
+			m.cancel()
+		}
+		go func() {
+			m.goroutines.Wait()
+			close(m.shutdownDone)
+		}()
+	}()
+
+	for _, inst := range m.instances {
+		if err := inst.start(); err != nil {
+			return err
+		}
 	}
+	m.summary = summary.NewRecorder(time.Now(), m.writerInterval)
 
-	// Initialize OBS stats
-	m.obsStats, err = metric.NewObsStats(m.client, m.metricInterval)
-	if err != nil {
-		return fmt.Errorf("failed to initialize OBS stats: %w", err)
+	if err := m.initializePingers(); err != nil {
+		return err
 	}
 
 	// Initialize system metrics
-	m.systemMetrics, err = metric.NewSystemMetrics(m.metricInterval)
+	systemMetrics, err := metric.NewSystemMetrics(m.metricInterval, m.connectionInfo.Aggregations)
 	if err != nil {
 		return fmt.Errorf("failed to initialize system metrics: %w", err)
 	}
+	m.startCollector(m.shared, systemMetrics)
 
-	// Initialize CSV writer if filename is provided
-	if m.connectionInfo.CSVFile != "" {
-		m.csvWriter, err = writer.NewCSVWriter(m.connectionInfo.CSVFile, version.ObsVersion, streamDomain)
+	sessionInfo := m.sessionInfo()
+	for _, spec := range m.outputSpecs() {
+		w, err := writer.New(spec, sessionInfo)
 		if err != nil {
-			return fmt.Errorf("failed to initialize CSV writer: %w", err)
+			return fmt.Errorf("failed to initialize output %s: %w", spec, err)
+		}
+		m.writers.Add(spec, w)
+		if spec != "console" {
+			m.logger.Info(fmt.Sprintf("Writing metrics to %s", spec), "output", spec)
 		}
-		fmt.Printf("Writing metrics to CSV file: %s\n", m.connectionInfo.CSVFile)
 	}
+	m.writers.Add("summary", m.summary)
 
-	// Initialize console writer
-	m.consoleWriter = writer.NewConsoleWriter()
+	for _, inst := range m.instances {
+		inst.columns = map[string]bool{}
+		for _, c := range sessionInfo.Columns {
+			inst.columns[c.Name] = true
+		}
+	}
 
 	m.PrintInfo()
 
-	// Start stream metrics monitoring in a goroutine
-	go func() {
-		if err := m.streamMetrics.Start(); err != nil {
-			fmt.Printf("Stream metrics error: %v\n", err)
+	// Columns can appear later, e.g. of outputs that are created while monitoring, so unknown metrics only warn
+	for _, rule := range m.alerts.Rules() {
+		if !slices.ContainsFunc(sessionInfo.Columns, func(c metric.Descriptor) bool { return c.Name == rule.Metric }) {
+			m.logger.Warn(fmt.Sprintf("Alert rule %s uses the unknown metric %s", rule.Name, rule.Metric), "rule", rule.Name, "metric", rule.Metric)
 		}
-	}()
+	}
 
-	// Start OBS stats monitoring in a goroutine
-	go func() {
-		if err := m.obsStats.Start(); err != nil {
-			fmt.Printf("OBS stats error: %v\n", err)
+	// Streams that are already live start a session right away
+	for _, inst := range m.instances {
+		if !inst.isOffline() {
+			inst.syncStreamState()
 		}
-	}()
+	}
 
-	// Start system metrics monitoring in a goroutine
+	// Start metrics collector
+	m.lastWrite.Store(time.Now().UnixNano())
+	m.goroutines.Add(1)
 	go func() {
-		if err := m.systemMetrics.Start(); err != nil {
-			fmt.Printf("System metrics error: %v\n", err)
-		}
+		defer m.goroutines.Done()
+		m.collectAndWriteMetrics()
 	}()
 
-	// Start metrics collector
-	go m.collectAndWriteMetrics()
-
 	// Monitor for disconnection and OBS exit
-	go m.monitorConnection()
+	for _, inst := range m.instances {
+		m.goroutines.Add(1)
+		go func() {
+			defer m.goroutines.Done()
+			inst.monitorConnection()
+		}()
+	}
 
 	return nil
 }
 
-func (m *Monitor) initializePingers(obsDomain string) error {
-	var err error
+// sessionInfo describes the columns of the rows: the columns of the instances followed by the shared columns
+func (m *Monitor) sessionInfo() writer.SessionInfo {
+	info := writer.SessionInfo{}
+	seenColumns := map[string]bool{}
+	seenCollectors := map[string]bool{}
+	versions := []string{}
+	domains := []string{}
+
+	for _, inst := range m.instances {
+		for _, column := range inst.registry.Describe() {
+			if !seenColumns[column.Name] {
+				seenColumns[column.Name] = true
+				info.Columns = append(info.Columns, column)
+			}
+		}
+		for _, name := range inst.registry.Names() {
+			if !seenCollectors[name] {
+				seenCollectors[name] = true
+				info.Collectors = append(info.Collectors, name)
+			}
+		}
 
-	m.obsPinger, err = metric.NewPinger(obsDomain, m.metricInterval)
-	if err != nil {
-		return fmt.Errorf("failed to initialize OBS pinger: %w", err)
+		if inst.name == "" {
+			versions = append(versions, inst.obsVersion)
+			domains = append(domains, inst.streamDomain)
+		} else {
+			info.Instances = append(info.Instances, inst.name)
+			versions = append(versions, inst.name+"="+inst.obsVersion)
+			domains = append(domains, inst.name+"="+inst.streamDomain)
+		}
 	}
 
-	m.googlePinger, err = metric.NewPinger("google.com", m.metricInterval)
-	if err != nil {
-		return fmt.Errorf("failed to initialize Google pinger: %w", err)
+	info.Columns = append(info.Columns, m.shared.Describe()...)
+	info.Collectors = append(info.Collectors, m.shared.Names()...)
+	info.ObsVersion = strings.Join(versions, ", ")
+	info.StreamDomain = strings.Join(domains, ", ")
+	return info
+}
+
+// dropsIdleRows reports whether the output drops the rows of idle instances when skip-idle is set,
+// these are the outputs that record every row
+func dropsIdleRows(spec string) bool {
+	kind, _, _ := strings.Cut(spec, ":")
+	return kind == "console" || kind == "csv" || kind == "jsonl"
+}
+
+// outputSpecs returns the configured outputs, the console is used when no outputs are given
+func (m *Monitor) outputSpecs() []string {
+	specs := slices.Clone(m.connectionInfo.Outputs)
+	if len(specs) == 0 {
+		specs = append(specs, "console")
 	}
+	if m.connectionInfo.CSVFile != "" {
+		specs = append(specs, "csv:"+m.connectionInfo.CSVFile)
+	}
+	if m.connectionInfo.PrometheusListen != "" {
+		specs = append(specs, "prometheus:"+m.connectionInfo.PrometheusListen)
+	}
+	return specs
+}
+
+// startCollector registers the collector and runs it in a goroutine until the monitor is shut down
+func (m *Monitor) startCollector(registry *metric.Registry, c metric.Collector) {
+	registry.Register(c)
 
+	m.goroutines.Add(1)
 	go func() {
-		if err := m.obsPinger.Start(); err != nil {
-			fmt.Printf("OBS pinger error: %v\n", err)
+		defer m.goroutines.Done()
+		if err := c.Start(m.ctx); err != nil {
+			m.logger.Error(fmt.Sprintf("Collector %s failed", c.Name()), "collector", c.Name(), "error", err)
 		}
 	}()
+}
 
-	go func() {
-		if err := m.googlePinger.Start(); err != nil {
-			fmt.Printf("Google pinger error: %v\n", err)
+// initializePingers starts a shared pinger per ping target
+func (m *Monitor) initializePingers() error {
+	targets := m.connectionInfo.PingTargets
+	if len(targets) == 0 {
+		targets = DefaultPingTargets
+	}
+
+	for _, target := range targets {
+		pinger, err := metric.NewPinger(target.Name, target.Host, m.metricInterval, m.connectionInfo.Aggregations)
+		if err != nil {
+			return fmt.Errorf("failed to initialize %s pinger: %w", target.Name, err)
 		}
-	}()
+		m.startCollector(m.shared, pinger)
+		m.pingers = append(m.pingers, pinger)
+		m.logger.Info(fmt.Sprintf("Pinging %s every %v", target.Host, m.metricInterval), "target", target.Name)
+	}
 
 	return nil
 }
 
 func (m *Monitor) PrintInfo() {
-	version, err := m.client.General.GetVersion()
-	if err != nil {
-		panic(err)
+	for _, inst := range m.instances {
+		inst.printInfo()
 	}
-
-	fmt.Printf("OBS Studio version: %s\n", version.ObsVersion)
-	fmt.Printf("Server protocol version: %s\n", version.ObsWebSocketVersion)
-	fmt.Printf("Client protocol version: %s\n", goobs.ProtocolVersion)
-	fmt.Printf("Client library version: %s\n\n", goobs.LibraryVersion)
 }
 
 func (m *Monitor) Close() {
-	if m.csvWriter != nil {
-		if err := m.csvWriter.Close(); err != nil {
-			fmt.Printf("Error closing CSV writer: %v\n", err)
+	if err := m.writers.Close(); err != nil {
+		m.logger.Error("Failed to close writers", "error", err)
+	}
+	for _, inst := range m.instances {
+		inst.disconnect()
+	}
+	// Notifiers like webhooks deliver their queued messages before they are closed
+	for _, n := range m.notifiers {
+		if closer, ok := n.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				m.logger.Error("Failed to close notifier", "error", err)
+			}
 		}
 	}
-	if m.client != nil {
-		m.client.Disconnect()
+	m.reportSummary()
+}
+
+// reportSummary prints the summary of the session and writes it next to every CSV file
+func (m *Monitor) reportSummary() {
+	if m.summary == nil {
+		return
+	}
+	report, ok := m.summary.Summary(time.Now())
+	if !ok {
+		return
+	}
+
+	for i := range report.Instances {
+		pingers := m.pingers
+		for _, inst := range m.instances {
+			if inst.name == report.Instances[i].Name && inst.pinger != nil {
+				pingers = append([]*metric.Pinger{inst.pinger}, pingers...)
+			}
+		}
+		for _, pinger := range pingers {
+			report.Instances[i].RTT[pinger.Target()] = summary.NewRTT(pinger.SessionStats())
+		}
+	}
+
+	report.Print(os.Stdout)
+
+	for _, spec := range m.outputSpecs() {
+		kind, target, _ := strings.Cut(spec, ":")
+		if kind != "csv" {
+			continue
+		}
+
+		filename := summary.Filename(target)
+		if err := report.WriteFile(filename); err != nil {
+			m.logger.Error("Failed to write session summary", "error", err)
+			continue
+		}
+		m.logger.Info(fmt.Sprintf("Wrote session summary to %s", filename), "file", filename)
 	}
 }
 
@@ -212,11 +509,13 @@ func (m *Monitor) Shutdown() {
 	m.cancel()
 }
 
+// Done is closed once the monitor has shut down and all of its goroutines have returned
 func (m *Monitor) Done() <-chan struct{} {
 	return m.shutdownDone
 }
 
-// collectAndWriteMetrics collects metrics from pingers, stream metrics, and system metrics and writes to CSV
+// collectAndWriteMetrics writes a row per OBS instance to all outputs every writer interval.
+// The shared collectors are collected once per interval and added to every row.
 func (m *Monitor) collectAndWriteMetrics() {
 	ticker := time.NewTicker(m.writerInterval)
 	defer ticker.Stop()
@@ -226,71 +525,87 @@ func (m *Monitor) collectAndWriteMetrics() {
 		case <-m.ctx.Done():
 			return
 		case <-ticker.C:
-			obsRTT, obsErr := m.obsPinger.GetAndResetMaxRTT()
-			googleRTT, googleErr := m.googlePinger.GetAndResetMaxRTT()
-			streamData := m.streamMetrics.GetAndResetMaxValues()
-			obsStatsData := m.obsStats.GetAndResetMaxValues()
-			systemMetricsData := m.systemMetrics.GetAndResetMaxValues()
-
-			m.writeMetrics(obsRTT, obsErr, googleRTT, googleErr, streamData, obsStatsData, systemMetricsData)
+			timestamp := time.Now()
+			shared, sharedErrs := m.shared.Collect()
+
+			// The rules of the shared columns are evaluated once, so they do not notify once per instance
+			sharedEvents := m.evaluateAlerts(writer.MetricsData{Timestamp: timestamp, Samples: shared})
+
+			for _, inst := range m.instances {
+				// Collect idle instances too, so their next row only covers this interval
+				samples, errs := inst.registry.Collect()
+
+				// The rules see the rows of idle instances too
+				events := inst.takeEvents()
+				events = append(events, m.evaluateAlerts(writer.MetricsData{Timestamp: timestamp, Instance: inst.name, Samples: samples})...)
+				events = append(events, sharedEvents...)
+
+				data := writer.MetricsData{
+					Timestamp: timestamp,
+					Instance:  inst.name,
+					Samples:   append(samples, shared...),
+					Events:    events,
+					Errors:    append(errs, sharedErrs...),
+				}
+				inst.reportNewColumns(data.Samples)
+
+				// Prometheus and the summary still get idle rows, so they never report a stream as live after it stopped
+				var skip func(string) bool
+				if m.connectionInfo.SkipIdle && !inst.isStreamActive() {
+					skip = dropsIdleRows
+				}
+
+				if err := m.writers.WriteMetricsExcept(data, skip); err != nil {
+					m.logger.Error("Failed to write metrics", "error", err)
+				}
+			}
+			m.lastWrite.Store(time.Now().UnixNano())
 		}
 	}
 }
 
-// writeMetrics writes a combined metrics row to CSV and console
-func (m *Monitor) writeMetrics(obsRTT time.Duration, obsErr error, googleRTT time.Duration, googleErr error, streamData metric.StreamMetricsData, obsStatsData metric.ObsStatsData, systemMetricsData metric.SystemMetricsData) {
-	data := writer.MetricsData{
-		Timestamp:           streamData.Timestamp,
-		ObsRTT:              obsRTT,
-		ObsPingError:        obsErr,
-		GoogleRTT:           googleRTT,
-		GooglePingError:     googleErr,
-		StreamActive:        streamData.Active,
-		OutputBytes:         streamData.OutputBytes,
-		OutputSkippedFrames: streamData.OutputSkippedFrames,
-		OutputFrames:        streamData.OutputFrames,
-		StreamError:         streamData.Error,
-		ObsCpuUsage:         obsStatsData.ObsCpuUsage,
-		ObsMemoryUsage:      obsStatsData.ObsMemoryUsage,
-		ObsStatsError:       obsStatsData.Error,
-		SystemCpuUsage:      systemMetricsData.CpuUsage,
-		SystemMemoryUsage:   systemMetricsData.MemoryUsage,
-		SystemMetricsError:  systemMetricsData.Error,
-	}
-
-	// Write to CSV if enabled
-	if m.csvWriter != nil {
-		if err := m.csvWriter.WriteMetrics(data); err != nil {
-			fmt.Printf("Error writing to CSV: %v\n", err)
+// evaluateAlerts evaluates the rules against a row, writes their alerts to the event log, notifies them
+// and returns their events
+func (m *Monitor) evaluateAlerts(data writer.MetricsData) []writer.Event {
+	var events []writer.Event
+	for _, a := range m.alerts.Evaluate(data) {
+		event := alertEvent(a)
+		events = append(events, event)
+		if err := m.writers.WriteEvent(event); err != nil {
+			m.logger.Error("Failed to write event", "error", err)
 		}
+		m.notify(a)
 	}
+	return events
+}
 
-	// Write to console
-	if err := m.consoleWriter.WriteMetrics(data); err != nil {
-		fmt.Printf("Error writing to console: %v\n", err)
+// LastWrite returns when the rows of a writer interval were last written, a stuck collector or writer stops it.
+// It is zero until the monitor is started.
+func (m *Monitor) LastWrite() time.Time {
+	nanos := m.lastWrite.Load()
+	if nanos == 0 {
+		return time.Time{}
 	}
+	return time.Unix(0, nanos)
 }
 
-func (m *Monitor) monitorConnection() {
-	defer close(m.shutdownDone)
+// notify passes an alert to all notifiers, a failing notifier does not prevent the others from receiving it
+func (m *Monitor) notify(a alert.Alert) {
+	for _, n := range m.notifiers {
+		if err := n.Notify(a); err != nil {
+			m.logger.Error("Failed to send alert", "rule", a.Rule, "error", err)
+		}
+	}
+}
 
-	listenDone := make(chan struct{})
-	go func() {
-		defer close(listenDone)
-		m.client.Listen(func(event any) {
-			switch event.(type) {
-			case *events.ExitStarted:
-				fmt.Println("\nOBS connection lost, closing obs-monitor...")
-				m.cancel()
+// notifyState passes a state change of an instance to the notifiers that report state changes
+func (m *Monitor) notifyState(change alert.StateChange) {
+	for _, n := range m.notifiers {
+		if sn, ok := n.(alert.StateNotifier); ok {
+			if err := sn.StateChanged(change); err != nil {
+				m.logger.Error("Failed to send state change", "change", string(change.Change), "error", err)
 			}
-		})
-	}()
-
-	select {
-	case <-m.ctx.Done():
-		m.client.Disconnect()
-		<-listenDone
-	case <-listenDone:
+		}
 	}
 }
 
@@ -311,3 +626,37 @@ func extractDomain(rawURL string) (string, error) {
 
 	return host, nil
 }
+
+// ingestAddress returns the host:port the stream is sent to, using the default port of the scheme when
+// the URL has none. SRT streams over UDP, so ok is false for it.
+func ingestAddress(rawURL string) (address string, ok bool, err error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "rtmp://" + rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, err
+	}
+
+	host := parsedURL.Hostname()
+	if host == "" {
+		return "", false, fmt.Errorf("no hostname found in URL")
+	}
+
+	port := parsedURL.Port()
+	switch strings.ToLower(parsedURL.Scheme) {
+	case "srt":
+		return "", false, nil
+	case "rtmps":
+		if port == "" {
+			port = "443"
+		}
+	default:
+		if port == "" {
+			port = "1935"
+		}
+	}
+
+	return net.JoinHostPort(host, port), true, nil
+}
@@ -1,8 +1,11 @@
 package monitor
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/webhook"
 )
 
 func TestExtractDomain_FullRTMPURL(t *testing.T) {
@@ -107,6 +110,210 @@ func TestExtractDomain_InvalidURLs(t *testing.T) {
 	}
 }
 
+func TestIngestAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "RTMP default port",
+			url:      "rtmp://live.twitch.tv/app",
+			expected: "live.twitch.tv:1935",
+			ok:       true,
+		},
+		{
+			name:     "RTMPS default port",
+			url:      "rtmps://a.rtmps.youtube.com:443/live2",
+			expected: "a.rtmps.youtube.com:443",
+			ok:       true,
+		},
+		{
+			name:     "RTMPS without port",
+			url:      "rtmps://live-api-s.facebook.com/rtmp/",
+			expected: "live-api-s.facebook.com:443",
+			ok:       true,
+		},
+		{
+			name:     "Explicit port",
+			url:      "rtmp://example.com:1936/live",
+			expected: "example.com:1936",
+			ok:       true,
+		},
+		{
+			name:     "Without protocol",
+			url:      "live.twitch.tv/app",
+			expected: "live.twitch.tv:1935",
+			ok:       true,
+		},
+		{
+			name: "SRT",
+			url:  "srt://example.com:9000?streamid=live",
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, ok, err := ingestAddress(tt.url)
+			if err != nil {
+				t.Fatalf("ingestAddress failed: %v", err)
+			}
+			if ok != tt.ok {
+				t.Errorf("Expected ok %v, got %v", tt.ok, ok)
+			}
+			if address != tt.expected {
+				t.Errorf("Expected address %s, got %s", tt.expected, address)
+			}
+		})
+	}
+}
+
+func TestIngestAddress_InvalidURL(t *testing.T) {
+	if _, _, err := ingestAddress("rtmp://"); err == nil {
+		t.Error("Expected error for URL without hostname")
+	}
+}
+
+func TestParsePingTarget(t *testing.T) {
+	target, err := ParsePingTarget("cdn_edge = edge.example.com")
+	if err != nil {
+		t.Fatalf("ParsePingTarget failed: %v", err)
+	}
+	if target.Name != "cdn_edge" || target.Host != "edge.example.com" {
+		t.Errorf("Expected cdn_edge=edge.example.com, got %+v", target)
+	}
+}
+
+func TestParsePingTarget_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{
+			name:  "Without name",
+			value: "edge.example.com",
+		},
+		{
+			name:  "Empty host",
+			value: "edge=",
+		},
+		{
+			name:  "Invalid name",
+			value: "cdn-edge=edge.example.com",
+		},
+		{
+			name:  "Uppercase name",
+			value: "Gateway=192.168.1.1",
+		},
+		{
+			name:  "Reserved name",
+			value: "obs=example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePingTarget(tt.value); err == nil {
+				t.Errorf("Expected error for %q", tt.value)
+			}
+		})
+	}
+}
+
+func TestNewMonitor_DuplicatePingTargets(t *testing.T) {
+	_, err := NewMonitor(ObsConnectionInfo{
+		MetricInterval: 1000,
+		WriterInterval: 1000,
+		PingTargets: []PingTarget{
+			{Name: "dns", Host: "1.1.1.1"},
+			{Name: "dns", Host: "8.8.8.8"},
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for duplicate ping target names")
+	}
+}
+
+func TestNewMonitor_InvalidWebhook(t *testing.T) {
+	_, err := NewMonitor(ObsConnectionInfo{
+		MetricInterval: 1000,
+		WriterInterval: 1000,
+		Webhooks:       []webhook.Endpoint{{URL: "ftp://example.com", Timeout: time.Second, RateLimit: 10}},
+	})
+	if err == nil {
+		t.Error("Expected error for a webhook URL that is not http")
+	}
+}
+
+func TestNewMonitor_Instances(t *testing.T) {
+	monitor, err := NewMonitor(ObsConnectionInfo{
+		Host:           "localhost:4455",
+		MetricInterval: 1000,
+		WriterInterval: 1000,
+		Instances: []Instance{
+			{Name: "main", Host: "localhost:4455"},
+			{Name: "backup", Host: "localhost:4456"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMonitor failed: %v", err)
+	}
+
+	if len(monitor.instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(monitor.instances))
+	}
+	if monitor.instances[1].name != "backup" || monitor.instances[1].host != "localhost:4456" {
+		t.Errorf("Unexpected instance %s on %s", monitor.instances[1].name, monitor.instances[1].host)
+	}
+}
+
+func TestNewMonitor_SingleUnnamedInstance(t *testing.T) {
+	monitor, err := NewMonitor(ObsConnectionInfo{Host: "localhost:4455", MetricInterval: 1000, WriterInterval: 1000})
+	if err != nil {
+		t.Fatalf("NewMonitor failed: %v", err)
+	}
+
+	if len(monitor.instances) != 1 || monitor.instances[0].name != "" {
+		t.Errorf("Expected a single unnamed instance, got %d", len(monitor.instances))
+	}
+}
+
+func TestValidateInstances(t *testing.T) {
+	err := ValidateInstances([]Instance{
+		{Name: "main", Host: "localhost:4455"},
+		{Name: "main", Host: "localhost:4456"},
+		{Name: "Backup", Host: "localhost:4457"},
+		{Name: "iso"},
+	})
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+
+	for _, message := range []string{"duplicate instance name main", `invalid instance name "Backup"`, "instance iso has no host"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q in %v", message, err)
+		}
+	}
+}
+
+func TestValidateAudio(t *testing.T) {
+	if err := ValidateAudio(Audio{Inputs: []string{"Mic/Aux"}, SilenceThresholdDB: -60, SilenceDuration: 5 * time.Second}); err != nil {
+		t.Errorf("Expected valid audio settings, got %v", err)
+	}
+
+	err := ValidateAudio(Audio{Inputs: []string{"Mic/Aux", "mic-aux"}, SilenceThresholdDB: 6, SilenceDuration: -time.Second})
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, message := range []string{"same column name mic_aux", "must not be above 0 dBFS", "must not be negative"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q in %v", message, err)
+		}
+	}
+}
+
 func TestNewMonitor_Initialization(t *testing.T) {
 	connInfo := ObsConnectionInfo{
 		Password:       "test-password",
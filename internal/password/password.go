@@ -0,0 +1,108 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+const (
+	// EnvVar holds the password for setups without a terminal, e.g. systemd units
+	EnvVar = "OBS_MONITOR_PASSWORD"
+	// KeyringService is the service name the password is stored under in the OS keyring
+	KeyringService = "obs-monitor"
+)
+
+// ErrNoPassword is returned when no source has a password and it cannot be asked for
+var ErrNoPassword = errors.New("no OBS WebSocket password found and no terminal to ask for it")
+
+// Resolver looks up the OBS WebSocket password, in order of precedence from:
+// the -password flag, the password file, the OBS_MONITOR_PASSWORD_<INSTANCE> and OBS_MONITOR_PASSWORD
+// environment variables, the OS keyring and finally an interactive prompt when a terminal is attached.
+type Resolver struct {
+	// Flag is the value of the -password flag, empty when not given
+	Flag string
+	// File is the path of a file holding the password, empty when not given
+	File string
+	// Account is the keyring account the password is stored under, the OBS host:port
+	Account string
+	// Instance is the name of the OBS instance, empty when a single instance is monitored
+	Instance string
+	// NoPrompt never asks for the password, e.g. when running as a daemon with a terminal attached
+	NoPrompt bool
+
+	isTerminal func() bool
+	prompt     func(instance string) (string, error)
+}
+
+func NewResolver(flag, file, account string) *Resolver {
+	return &Resolver{
+		Flag:       flag,
+		File:       file,
+		Account:    account,
+		isTerminal: func() bool { return term.IsTerminal(int(syscall.Stdin)) },
+		prompt:     prompt,
+	}
+}
+
+// Resolve returns the password of the first source that has one.
+// The environment variable counts when it is set, even when it is empty, for OBS without authentication.
+func (r *Resolver) Resolve() (string, error) {
+	if r.Flag != "" {
+		return r.Flag, nil
+	}
+
+	if r.File != "" {
+		data, err := os.ReadFile(r.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if r.Instance != "" {
+		if password, ok := os.LookupEnv(InstanceEnvVar(r.Instance)); ok {
+			return password, nil
+		}
+	}
+	if password, ok := os.LookupEnv(EnvVar); ok {
+		return password, nil
+	}
+
+	password, err := keyring.Get(KeyringService, r.Account)
+	if err == nil {
+		return password, nil
+	}
+
+	if r.NoPrompt || !r.isTerminal() {
+		if !errors.Is(err, keyring.ErrNotFound) {
+			return "", fmt.Errorf("%w (keyring: %v)", ErrNoPassword, err)
+		}
+		return "", ErrNoPassword
+	}
+	return r.prompt(r.Instance)
+}
+
+// InstanceEnvVar returns the environment variable holding the password of a single instance, e.g. OBS_MONITOR_PASSWORD_MAIN
+func InstanceEnvVar(instance string) string {
+	return EnvVar + "_" + strings.ToUpper(instance)
+}
+
+func prompt(instance string) (string, error) {
+	if instance != "" {
+		fmt.Printf("Enter OBS WebSocket password for %s: ", instance)
+	} else {
+		fmt.Print("Enter OBS WebSocket password: ")
+	}
+	passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(passwordBytes), nil
+}
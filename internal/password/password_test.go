@@ -0,0 +1,154 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// newTestResolver returns a resolver with an empty mock keyring and without terminal
+func newTestResolver(t *testing.T, flag, file string) *Resolver {
+	t.Helper()
+	keyring.MockInit()
+	// Setenv restores the variable after the test
+	t.Setenv(EnvVar, "")
+	os.Unsetenv(EnvVar)
+
+	r := NewResolver(flag, file, "localhost:4455")
+	r.isTerminal = func() bool { return false }
+	r.prompt = func(instance string) (string, error) {
+		t.Error("Expected no prompt")
+		return "", nil
+	}
+	return r
+}
+
+func writePasswordFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	return path
+}
+
+func TestResolve_Precedence(t *testing.T) {
+	file := writePasswordFile(t, "from-file\n")
+
+	tests := []struct {
+		name     string
+		flag     string
+		file     string
+		env      *string
+		keyring  string
+		expected string
+	}{
+		{name: "Flag", flag: "from-flag", file: file, env: ptr("from-env"), keyring: "from-keyring", expected: "from-flag"},
+		{name: "File", file: file, env: ptr("from-env"), keyring: "from-keyring", expected: "from-file"},
+		{name: "Environment", env: ptr("from-env"), keyring: "from-keyring", expected: "from-env"},
+		{name: "Empty environment", env: ptr(""), keyring: "from-keyring", expected: ""},
+		{name: "Keyring", keyring: "from-keyring", expected: "from-keyring"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestResolver(t, tt.flag, tt.file)
+			if tt.env != nil {
+				t.Setenv(EnvVar, *tt.env)
+			}
+			if tt.keyring != "" {
+				if err := keyring.Set(KeyringService, "localhost:4455", tt.keyring); err != nil {
+					t.Fatalf("Failed to store password in keyring: %v", err)
+				}
+			}
+
+			password, err := r.Resolve()
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if password != tt.expected {
+				t.Errorf("Expected password %q, got %q", tt.expected, password)
+			}
+		})
+	}
+}
+
+func TestResolve_InstanceEnvironment(t *testing.T) {
+	r := newTestResolver(t, "", "")
+	r.Instance = "backup"
+	t.Setenv(EnvVar, "shared")
+	t.Setenv("OBS_MONITOR_PASSWORD_BACKUP", "backup-only")
+
+	password, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if password != "backup-only" {
+		t.Errorf("Expected the password of the instance, got %q", password)
+	}
+
+	r.Instance = "main"
+	password, err = r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if password != "shared" {
+		t.Errorf("Expected the shared password, got %q", password)
+	}
+}
+
+func TestResolve_KeyringAccount(t *testing.T) {
+	r := newTestResolver(t, "", "")
+	keyring.Set(KeyringService, "studio:4455", "other-instance")
+
+	if _, err := r.Resolve(); !errors.Is(err, ErrNoPassword) {
+		t.Errorf("Expected ErrNoPassword for a password of another account, got %v", err)
+	}
+}
+
+func TestResolve_NoTerminal(t *testing.T) {
+	r := newTestResolver(t, "", "")
+
+	if _, err := r.Resolve(); !errors.Is(err, ErrNoPassword) {
+		t.Errorf("Expected ErrNoPassword, got %v", err)
+	}
+}
+
+func TestResolve_Prompt(t *testing.T) {
+	r := newTestResolver(t, "", "")
+	r.isTerminal = func() bool { return true }
+	r.prompt = func(instance string) (string, error) { return "typed", nil }
+
+	password, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if password != "typed" {
+		t.Errorf("Expected prompted password, got %q", password)
+	}
+}
+
+func TestResolve_NoPrompt(t *testing.T) {
+	r := newTestResolver(t, "", "")
+	r.isTerminal = func() bool { return true }
+	r.NoPrompt = true
+
+	if _, err := r.Resolve(); !errors.Is(err, ErrNoPassword) {
+		t.Errorf("Expected ErrNoPassword without prompt, got %v", err)
+	}
+}
+
+func TestResolve_MissingFile(t *testing.T) {
+	r := newTestResolver(t, "", filepath.Join(t.TempDir(), "missing"))
+
+	if _, err := r.Resolve(); err == nil {
+		t.Error("Expected error for a missing password file")
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,269 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
+)
+
+// Summary reports a monitoring session, e.g. for post-show reports
+type Summary struct {
+	Started         time.Time  `json:"started"`
+	Stopped         time.Time  `json:"stopped"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	Instances       []Instance `json:"instances"`
+}
+
+// Instance summarizes the rows of a single OBS instance
+type Instance struct {
+	Name string `json:"instance,omitempty"`
+	// LiveSeconds is the time the stream was active
+	LiveSeconds            float64 `json:"live_seconds"`
+	OutputBytes            float64 `json:"output_bytes"`
+	AverageBitrateKbps     float64 `json:"average_bitrate_kbps"`
+	OutputFrames           float64 `json:"output_frames"`
+	OutputSkippedFrames    float64 `json:"output_skipped_frames"`
+	OutputSkippedFramesPct float64 `json:"output_skipped_frames_pct"`
+	// StreamReconnects counts the reconnects of the stream output to the server
+	StreamReconnects int `json:"stream_reconnects"`
+	// RTT holds the RTT statistics per ping target
+	RTT                     map[string]RTT `json:"rtt_ms"`
+	PeakObsCPUPercent       float64        `json:"peak_obs_cpu_percent"`
+	PeakObsMemoryMB         float64        `json:"peak_obs_memory_mb"`
+	PeakSystemCPUPercent    float64        `json:"peak_system_cpu_percent"`
+	PeakSystemMemoryPercent float64        `json:"peak_system_memory_percent"`
+	// ErrorIntervals counts the writer intervals in which a collector reported an error, by collector
+	ErrorIntervals map[string]int `json:"error_intervals"`
+	Reconnects     int            `json:"reconnects"`
+}
+
+// RTT summarizes the round-trip times of a ping target, Count is 0 when no ping was answered
+type RTT struct {
+	Min   float64 `json:"min"`
+	Avg   float64 `json:"avg"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// NewRTT converts the statistics of a pinger
+func NewRTT(stats metric.Stats) RTT {
+	return RTT(stats)
+}
+
+// Recorder accumulates the rows of a monitoring session, it is added to the writers of the monitor
+type Recorder struct {
+	started        time.Time
+	writerInterval time.Duration
+	// instances holds the totals per OBS instance, in the order they were first seen
+	instances map[string]*totals
+	order     []string
+	mu        sync.Mutex
+}
+
+type totals struct {
+	rows             int
+	live             time.Duration
+	bytes            float64
+	frames           float64
+	skippedFrames    float64
+	streamReconnects float64
+	peaks            map[string]float64
+	errorIntervals   map[string]int
+	reconnects       int
+	// lastRow is the timestamp of the previous row
+	lastRow time.Time
+}
+
+// peakColumns are the columns whose maximum is reported
+var peakColumns = []string{"obs_cpu_percent", "obs_memory_mb", "system_cpu_percent", "system_memory_percent"}
+
+// maxRowIntervals caps the time a row covers in writer intervals, so a stalled monitor does not count the stall as live time
+const maxRowIntervals = 3
+
+// NewRecorder creates a recorder for a session started at started, every row covers the time since the previous row
+func NewRecorder(started time.Time, writerInterval time.Duration) *Recorder {
+	return &Recorder{
+		started:        started,
+		writerInterval: writerInterval,
+		instances:      map[string]*totals{},
+	}
+}
+
+func (r *Recorder) instance(name string) *totals {
+	t, ok := r.instances[name]
+	if !ok {
+		t = &totals{peaks: map[string]float64{}, errorIntervals: map[string]int{}}
+		r.instances[name] = t
+		r.order = append(r.order, name)
+	}
+	return t
+}
+
+// WriteMetrics adds the row to the totals of its instance
+func (r *Recorder) WriteMetrics(data writer.MetricsData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.instance(data.Instance)
+	t.rows++
+
+	previous := t.lastRow
+	if previous.IsZero() {
+		previous = r.started
+	}
+	covered := min(max(data.Timestamp.Sub(previous), 0), maxRowIntervals*r.writerInterval)
+	t.lastRow = data.Timestamp
+
+	if s, ok := data.Sample("stream_active"); ok && s.Valid && s.Value != 0 {
+		t.live += covered
+	}
+	t.bytes += value(data, "output_bytes")
+	t.frames += value(data, "output_frames")
+	t.skippedFrames += value(data, "output_skipped_frames")
+	t.streamReconnects += value(data, "output_reconnects")
+
+	for _, name := range peakColumns {
+		if s, ok := data.Sample(name); ok && s.Valid && s.Value > t.peaks[name] {
+			t.peaks[name] = s.Value
+		}
+	}
+
+	// A collector can report multiple errors in an interval, they count once
+	collectors := map[string]bool{}
+	for _, e := range data.Errors {
+		collectors[e.Collector] = true
+	}
+	for collector := range collectors {
+		t.errorIntervals[collector]++
+	}
+
+	return nil
+}
+
+func value(data writer.MetricsData, name string) float64 {
+	if s, ok := data.Sample(name); ok && s.Valid {
+		return s.Value
+	}
+	return 0
+}
+
+// Reconnected counts a reconnect of an instance
+func (r *Recorder) Reconnected(instance string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instance(instance).reconnects++
+}
+
+// Close implements writer.Writer, the summary is still available afterwards
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Summary returns the summary of the session stopped at stopped, ok is false when no rows were written
+func (r *Recorder) Summary(stopped time.Time) (summary Summary, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary = Summary{
+		Started:         r.started,
+		Stopped:         stopped,
+		DurationSeconds: stopped.Sub(r.started).Seconds(),
+	}
+	for _, name := range r.order {
+		t := r.instances[name]
+		if t.rows == 0 {
+			continue
+		}
+
+		inst := Instance{
+			Name:                    name,
+			LiveSeconds:             t.live.Seconds(),
+			OutputBytes:             t.bytes,
+			OutputFrames:            t.frames,
+			OutputSkippedFrames:     t.skippedFrames,
+			StreamReconnects:        int(t.streamReconnects),
+			RTT:                     map[string]RTT{},
+			PeakObsCPUPercent:       t.peaks["obs_cpu_percent"],
+			PeakObsMemoryMB:         t.peaks["obs_memory_mb"],
+			PeakSystemCPUPercent:    t.peaks["system_cpu_percent"],
+			PeakSystemMemoryPercent: t.peaks["system_memory_percent"],
+			ErrorIntervals:          maps.Clone(t.errorIntervals),
+			Reconnects:              t.reconnects,
+		}
+		if t.live > 0 {
+			inst.AverageBitrateKbps = t.bytes * 8 / 1000 / t.live.Seconds()
+		}
+		if t.frames > 0 {
+			inst.OutputSkippedFramesPct = t.skippedFrames / t.frames * 100
+		}
+		summary.Instances = append(summary.Instances, inst)
+	}
+
+	return summary, len(summary.Instances) > 0
+}
+
+// WriteFile writes the summary as indented JSON
+func (s Summary) WriteFile(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0o666); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	return nil
+}
+
+// Print writes the summary in a human readable form
+func (s Summary) Print(w io.Writer) {
+	fmt.Fprintln(w, "\nSession summary")
+	fmt.Fprintf(w, "  %-18s %v\n", "Duration:", time.Duration(s.DurationSeconds*float64(time.Second)).Round(time.Second))
+
+	for _, inst := range s.Instances {
+		if inst.Name != "" {
+			fmt.Fprintf(w, "Instance %s\n", inst.Name)
+		}
+		fmt.Fprintf(w, "  %-18s %v\n", "Live:", time.Duration(inst.LiveSeconds*float64(time.Second)).Round(time.Second))
+		fmt.Fprintf(w, "  %-18s %.2f MB, average %.0f kbps\n", "Output:", inst.OutputBytes/1000/1000, inst.AverageBitrateKbps)
+		fmt.Fprintf(w, "  %-18s %.0f of %.0f (%.2f%%)\n", "Skipped frames:", inst.OutputSkippedFrames, inst.OutputFrames, inst.OutputSkippedFramesPct)
+		fmt.Fprintf(w, "  %-18s %d\n", "Stream reconnects:", inst.StreamReconnects)
+
+		for _, target := range slices.Sorted(maps.Keys(inst.RTT)) {
+			rtt := inst.RTT[target]
+			label := fmt.Sprintf("RTT %s:", target)
+			if rtt.Count == 0 {
+				fmt.Fprintf(w, "  %-18s no replies\n", label)
+				continue
+			}
+			fmt.Fprintf(w, "  %-18s min %.2f / avg %.2f / p95 %.2f / max %.2f ms\n", label, rtt.Min, rtt.Avg, rtt.P95, rtt.Max)
+		}
+
+		fmt.Fprintf(w, "  %-18s CPU %.1f%%, memory %.0f MB\n", "Peak OBS:", inst.PeakObsCPUPercent, inst.PeakObsMemoryMB)
+		fmt.Fprintf(w, "  %-18s CPU %.1f%%, memory %.1f%%\n", "Peak system:", inst.PeakSystemCPUPercent, inst.PeakSystemMemoryPercent)
+		fmt.Fprintf(w, "  %-18s %d\n", "Reconnects:", inst.Reconnects)
+
+		errors := []string{}
+		for _, collector := range slices.Sorted(maps.Keys(inst.ErrorIntervals)) {
+			errors = append(errors, fmt.Sprintf("%s %d", collector, inst.ErrorIntervals[collector]))
+		}
+		if len(errors) == 0 {
+			errors = append(errors, "none")
+		}
+		fmt.Fprintf(w, "  %-18s %s\n", "Error intervals:", strings.Join(errors, ", "))
+	}
+}
+
+// Filename returns the summary file of a CSV file, e.g. metrics.summary.json for metrics.csv
+func Filename(csvFile string) string {
+	return strings.TrimSuffix(csvFile, ".csv") + ".summary.json"
+}
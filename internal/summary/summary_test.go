@@ -0,0 +1,198 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+	"github.com/joepadmiraal/obs-monitor/internal/writer"
+)
+
+var (
+	streamActive  = metric.Descriptor{Name: "stream_active", Kind: metric.Bool}
+	outputBytes   = metric.Descriptor{Name: "output_bytes", Kind: metric.Counter}
+	outputFrames  = metric.Descriptor{Name: "output_frames", Kind: metric.Counter}
+	skippedFrames = metric.Descriptor{Name: "output_skipped_frames", Kind: metric.Counter}
+	reconnects    = metric.Descriptor{Name: "output_reconnects", Kind: metric.Counter}
+	obsCPU        = metric.Descriptor{Name: "obs_cpu_percent"}
+	systemMemory  = metric.Descriptor{Name: "system_memory_percent"}
+)
+
+var started = time.Date(2025, 12, 23, 20, 0, 0, 0, time.UTC)
+
+func row(instance string, active bool, bytes, cpu float64, errs ...metric.CollectorError) writer.MetricsData {
+	return writer.MetricsData{
+		Timestamp: started,
+		Instance:  instance,
+		Samples: []metric.Sample{
+			streamActive.BoolSample(active),
+			outputBytes.Sample(bytes),
+			outputFrames.Sample(30),
+			skippedFrames.Sample(3),
+			reconnects.Sample(0),
+			obsCPU.Sample(cpu),
+			systemMemory.Sample(50),
+		},
+		Errors: errs,
+	}
+}
+
+func TestRecorder_Summary(t *testing.T) {
+	r := NewRecorder(started, time.Second)
+	pingErr := metric.CollectorError{Collector: "google_ping", Err: errors.New("timeout")}
+
+	idle := row("", false, 0, 5)
+	idle.Timestamp = started.Add(time.Second)
+	r.WriteMetrics(idle)
+	live := row("", true, 500_000, 20, pingErr, pingErr)
+	live.Timestamp = started.Add(2 * time.Second)
+	r.WriteMetrics(live)
+	reconnected := row("", true, 1_500_000, 10, pingErr)
+	reconnected.Samples[4] = reconnects.Sample(2)
+	reconnected.Timestamp = started.Add(3 * time.Second)
+	r.WriteMetrics(reconnected)
+	r.Reconnected("")
+
+	summary, ok := r.Summary(started.Add(time.Minute))
+	if !ok {
+		t.Fatal("Expected a summary")
+	}
+	if summary.DurationSeconds != 60 {
+		t.Errorf("Expected a duration of 60s, got %v", summary.DurationSeconds)
+	}
+	if len(summary.Instances) != 1 {
+		t.Fatalf("Expected a single instance, got %d", len(summary.Instances))
+	}
+
+	inst := summary.Instances[0]
+	if inst.LiveSeconds != 2 || inst.OutputBytes != 2_000_000 {
+		t.Errorf("Expected 2s live and 2MB, got %vs and %v bytes", inst.LiveSeconds, inst.OutputBytes)
+	}
+	if inst.AverageBitrateKbps != 8000 {
+		t.Errorf("Expected an average bitrate of 8000 kbps, got %v", inst.AverageBitrateKbps)
+	}
+	if inst.OutputFrames != 90 || inst.OutputSkippedFrames != 9 || inst.OutputSkippedFramesPct != 10 {
+		t.Errorf("Expected 9 of 90 frames skipped, got %+v", inst)
+	}
+	if inst.PeakObsCPUPercent != 20 || inst.PeakSystemMemoryPercent != 50 {
+		t.Errorf("Expected peaks 20 and 50, got %v and %v", inst.PeakObsCPUPercent, inst.PeakSystemMemoryPercent)
+	}
+	if inst.ErrorIntervals["google_ping"] != 2 {
+		t.Errorf("Expected 2 error intervals, got %v", inst.ErrorIntervals)
+	}
+	if inst.Reconnects != 1 {
+		t.Errorf("Expected 1 reconnect, got %d", inst.Reconnects)
+	}
+	if inst.StreamReconnects != 2 {
+		t.Errorf("Expected 2 stream reconnects, got %d", inst.StreamReconnects)
+	}
+}
+
+func TestRecorder_LiveTimeFollowsTimestamps(t *testing.T) {
+	r := NewRecorder(started, time.Second)
+
+	// A late row covers the time since the previous row, a stall is capped at a few writer intervals
+	for _, at := range []time.Duration{time.Second, 2500 * time.Millisecond, time.Minute} {
+		data := row("", true, 1000, 10)
+		data.Timestamp = started.Add(at)
+		r.WriteMetrics(data)
+	}
+
+	summary, _ := r.Summary(started.Add(time.Minute))
+	if live := summary.Instances[0].LiveSeconds; live != 5.5 {
+		t.Errorf("Expected 5.5s live, got %vs", live)
+	}
+}
+
+func TestRecorder_Instances(t *testing.T) {
+	r := NewRecorder(started, time.Second)
+	r.WriteMetrics(row("main", true, 1000, 10))
+	r.WriteMetrics(row("backup", true, 2000, 20))
+
+	summary, _ := r.Summary(started.Add(time.Second))
+	if len(summary.Instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(summary.Instances))
+	}
+	if summary.Instances[0].Name != "main" || summary.Instances[1].OutputBytes != 2000 {
+		t.Errorf("Expected the totals per instance in order, got %+v", summary.Instances)
+	}
+}
+
+func TestRecorder_NoRows(t *testing.T) {
+	r := NewRecorder(started, time.Second)
+	r.Reconnected("")
+
+	if _, ok := r.Summary(started.Add(time.Second)); ok {
+		t.Error("Expected no summary without rows")
+	}
+}
+
+func TestSummary_WriteFile(t *testing.T) {
+	r := NewRecorder(started, time.Second)
+	r.WriteMetrics(row("", true, 1000, 10))
+	summary, _ := r.Summary(started.Add(time.Second))
+	summary.Instances[0].RTT["obs"] = NewRTT(metric.Stats{Min: 1, Avg: 2, P95: 3, Max: 4, Count: 5})
+
+	filename := filepath.Join(t.TempDir(), "metrics.summary.json")
+	if err := summary.WriteFile(filename); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read summary: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+
+	inst := decoded["instances"].([]any)[0].(map[string]any)
+	if _, ok := inst["instance"]; ok {
+		t.Error("Expected no instance name for a single instance")
+	}
+	rtt := inst["rtt_ms"].(map[string]any)["obs"].(map[string]any)
+	if rtt["p95"] != 3.0 {
+		t.Errorf("Expected the p95 RTT, got %v", rtt)
+	}
+}
+
+func TestSummary_Print(t *testing.T) {
+	r := NewRecorder(started, time.Second)
+	r.WriteMetrics(row("main", true, 1000, 10))
+	summary, _ := r.Summary(started.Add(90 * time.Second))
+	summary.Instances[0].RTT["obs"] = NewRTT(metric.Stats{Min: 1, Avg: 2, P95: 3, Max: 4, Count: 5})
+	summary.Instances[0].RTT["google"] = RTT{}
+
+	var buf bytes.Buffer
+	summary.Print(&buf)
+	output := buf.String()
+
+	for _, expected := range []string{
+		"Duration:          1m30s",
+		"Instance main",
+		"RTT obs:           min 1.00 / avg 2.00 / p95 3.00 / max 4.00 ms",
+		"RTT google:        no replies",
+		"Stream reconnects: 0",
+		"Error intervals:   none",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, output)
+		}
+	}
+}
+
+func TestFilename(t *testing.T) {
+	if got := Filename("obs-monitor-2025-12-23.csv"); got != "obs-monitor-2025-12-23.summary.json" {
+		t.Errorf("Expected the .csv extension to be replaced, got %s", got)
+	}
+	if got := Filename("metrics"); got != "metrics.summary.json" {
+		t.Errorf("Expected the suffix to be appended, got %s", got)
+	}
+}
@@ -0,0 +1,347 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
+)
+
+// Payload formats, the chat formats post a single line of text
+const (
+	FormatGeneric = "generic"
+	FormatSlack   = "slack"
+	FormatDiscord = "discord"
+	FormatTeams   = "teams"
+)
+
+// Formats lists all payload formats
+var Formats = []string{FormatGeneric, FormatSlack, FormatDiscord, FormatTeams}
+
+// Defaults of the endpoint settings that are not configured
+const (
+	DefaultTimeout   = 5 * time.Second
+	DefaultRetries   = 3
+	DefaultRateLimit = 10
+)
+
+const (
+	// queueSize is the number of messages that wait for delivery, further messages are dropped
+	queueSize         = 100
+	retryInitialDelay = time.Second
+	// closeTimeout is how long Close waits for the queued messages, so a shutdown does not hang on an unreachable endpoint
+	closeTimeout = 3 * time.Second
+)
+
+// Endpoint is an HTTP endpoint that receives the alerts and state changes as JSON
+type Endpoint struct {
+	URL string
+	// Format is the payload format, one of Formats, generic when empty
+	Format  string
+	Timeout time.Duration
+	// Retries is the number of retries of a request that failed with a network error, a 5xx or a 429 status
+	Retries int
+	// RateLimit is the maximum number of messages per minute, further messages are dropped and counted in the next message
+	RateLimit int
+}
+
+// Validate checks the URL, the format and the limits of the endpoint
+func (e Endpoint) Validate() error {
+	var errs []error
+	if u, err := url.Parse(e.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		// The URL is left out as chat webhooks keep their secret in it
+		errs = append(errs, fmt.Errorf("invalid webhook URL, expected an http or https URL"))
+	}
+	if e.Format != "" && !slices.Contains(Formats, e.Format) {
+		errs = append(errs, fmt.Errorf("unknown webhook format %q, expected one of %s", e.Format, strings.Join(Formats, ", ")))
+	}
+	if e.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("webhook timeout must be positive, got %v", e.Timeout))
+	}
+	if e.Retries < 0 {
+		errs = append(errs, fmt.Errorf("webhook retries must not be negative, got %d", e.Retries))
+	}
+	if e.RateLimit <= 0 {
+		errs = append(errs, fmt.Errorf("webhook rate limit must be positive, got %d", e.RateLimit))
+	}
+	return errors.Join(errs...)
+}
+
+// Webhook posts the alerts and state changes to an endpoint. The messages are delivered in the background,
+// so a slow endpoint does not delay the rows.
+type Webhook struct {
+	endpoint Endpoint
+	client   *http.Client
+	logger   *slog.Logger
+	queue    chan message
+	// sent holds the times of the messages of the last minute, dropped counts the messages over the rate limit
+	sent       []time.Time
+	dropped    int
+	retryDelay time.Duration
+	// ctx is canceled when Close times out, it aborts the request in flight
+	ctx          context.Context
+	cancel       context.CancelFunc
+	closeTimeout time.Duration
+	closed       bool
+	closing      chan struct{}
+	done         chan struct{}
+	mu           sync.Mutex
+}
+
+// message is the content of a notification, it is rendered in the format of the endpoint
+type message struct {
+	Timestamp time.Time `json:"timestamp"`
+	Instance  string    `json:"instance,omitempty"`
+	// Type is the change of a state change or alert for alerts
+	Type     string       `json:"type"`
+	Severity string       `json:"severity"`
+	Text     string       `json:"message"`
+	Alert    *alertFields `json:"alert,omitempty"`
+	// Dropped counts the messages that were dropped by the rate limit before this one
+	Dropped int `json:"dropped,omitempty"`
+}
+
+type alertFields struct {
+	Rule      string  `json:"rule"`
+	Metric    string  `json:"metric"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+	State     string  `json:"state"`
+}
+
+// New creates a webhook and starts its delivery, Close stops it
+func New(endpoint Endpoint, logger *slog.Logger) (*Webhook, error) {
+	if err := endpoint.Validate(); err != nil {
+		return nil, err
+	}
+	if endpoint.Format == "" {
+		endpoint.Format = FormatGeneric
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Webhook{
+		endpoint:     endpoint,
+		client:       &http.Client{Timeout: endpoint.Timeout},
+		logger:       logger.With("webhook", redact(endpoint.URL)),
+		queue:        make(chan message, queueSize),
+		retryDelay:   retryInitialDelay,
+		ctx:          ctx,
+		cancel:       cancel,
+		closeTimeout: closeTimeout,
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Notify sends an alert
+func (w *Webhook) Notify(a alert.Alert) error {
+	text := a.Message()
+	if a.Instance != "" {
+		text = a.Instance + ": " + text
+	}
+
+	return w.enqueue(message{
+		Timestamp: a.Timestamp,
+		Instance:  a.Instance,
+		Type:      "alert",
+		Severity:  a.Severity,
+		Text:      "Alert " + text,
+		Alert: &alertFields{
+			Rule:      a.Rule,
+			Metric:    a.Metric,
+			Operator:  a.Operator,
+			Threshold: a.Threshold,
+			Value:     a.Value,
+			State:     string(a.State),
+		},
+	})
+}
+
+// StateChanged sends a state change, a lost connection is a warning
+func (w *Webhook) StateChanged(change alert.StateChange) error {
+	severity := "info"
+	if change.Change == alert.Disconnected {
+		severity = "warning"
+	}
+
+	return w.enqueue(message{
+		Timestamp: change.Timestamp,
+		Instance:  change.Instance,
+		Type:      string(change.Change),
+		Severity:  severity,
+		Text:      change.Message,
+	})
+}
+
+func (w *Webhook) enqueue(msg message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("webhook is closed")
+	}
+
+	// The rate limit is a sliding window of a minute, so a flapping network does not spam the channel
+	now := time.Now()
+	w.sent = slices.DeleteFunc(w.sent, func(t time.Time) bool { return now.Sub(t) >= time.Minute })
+	if len(w.sent) >= w.endpoint.RateLimit {
+		w.dropped++
+		return nil
+	}
+
+	msg.Dropped = w.dropped
+	select {
+	case w.queue <- msg:
+		w.sent = append(w.sent, now)
+		w.dropped = 0
+		return nil
+	default:
+		w.dropped++
+		return fmt.Errorf("webhook queue is full, dropped %s", msg.Text)
+	}
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+
+	// Once Close timed out, the remaining messages are dropped
+	dropped := 0
+	for msg := range w.queue {
+		if w.ctx.Err() != nil {
+			dropped++
+			continue
+		}
+		if err := w.deliver(msg); err != nil {
+			if w.ctx.Err() != nil {
+				dropped++
+				continue
+			}
+			w.logger.Error("Failed to send webhook", "error", err)
+		}
+	}
+	if dropped > 0 {
+		w.logger.Warn(fmt.Sprintf("Dropped %d webhook messages that were not delivered within %v of the shutdown", dropped, w.closeTimeout))
+	}
+}
+
+// deliver posts a message, retrying with exponential backoff until the webhook is closed
+func (w *Webhook) deliver(msg message) error {
+	body, err := w.payload(msg)
+	if err != nil {
+		return err
+	}
+
+	delay := w.retryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.endpoint.Retries {
+			return err
+		}
+
+		select {
+		case <-w.closing:
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends a payload, retry reports whether a failure is worth retrying
+func (w *Webhook) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		// The error of the client holds the URL, which is left out as it can contain a secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// payload renders a message in the format of the endpoint
+func (w *Webhook) payload(msg message) ([]byte, error) {
+	var v any = msg
+	if w.endpoint.Format != FormatGeneric {
+		text := fmt.Sprintf("[%s] %s", msg.Severity, msg.Text)
+		if msg.Dropped > 0 {
+			text += fmt.Sprintf(" (%d earlier messages dropped by the rate limit)", msg.Dropped)
+		}
+
+		switch w.endpoint.Format {
+		case FormatDiscord:
+			v = map[string]string{"content": text}
+		default:
+			// Slack and Teams incoming webhooks both take the text field
+			v = map[string]string{"text": text}
+		}
+	}
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	return body, nil
+}
+
+// Close stops accepting messages and waits until the queued messages are delivered, without further retries.
+// The messages that are not delivered within closeTimeout are dropped.
+func (w *Webhook) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	close(w.closing)
+	w.mu.Unlock()
+
+	defer w.cancel()
+	select {
+	case <-w.done:
+	case <-time.After(w.closeTimeout):
+		w.cancel()
+		<-w.done
+	}
+	return nil
+}
+
+// redact hides the path of a webhook URL in logs, chat webhooks keep their secret in the path
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,303 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
+)
+
+// receiver is an endpoint that records the payloads, it answers with the statuses in order and 200 afterwards
+type receiver struct {
+	server   *httptest.Server
+	payloads []map[string]any
+	statuses []int
+	mu       sync.Mutex
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			t.Errorf("Expected a JSON payload: %v", err)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.payloads = append(r.payloads, payload)
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *receiver) received() []map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.payloads
+}
+
+// wait waits until the endpoint received n requests, as Close does not wait for retries
+func (r *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(r.received()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d requests, got %d", n, len(r.received()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newWebhook(t *testing.T, endpoint Endpoint) *Webhook {
+	t.Helper()
+	if endpoint.Timeout == 0 {
+		endpoint.Timeout = time.Second
+	}
+	if endpoint.RateLimit == 0 {
+		endpoint.RateLimit = DefaultRateLimit
+	}
+
+	w, err := New(endpoint, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	w.retryDelay = time.Millisecond
+	return w
+}
+
+var firing = alert.Alert{
+	Timestamp: time.Date(2025, 12, 23, 15, 1, 25, 0, time.UTC),
+	Instance:  "backup",
+	Rule:      "high-rtt",
+	Metric:    "obs_rtt_ms",
+	Operator:  ">",
+	Threshold: 150,
+	Value:     154.2,
+	Severity:  "critical",
+	State:     alert.Firing,
+}
+
+func TestWebhook_Generic(t *testing.T) {
+	r := newReceiver(t)
+	w := newWebhook(t, Endpoint{URL: r.server.URL})
+
+	w.Notify(firing)
+	w.StateChanged(alert.StateChange{Timestamp: firing.Timestamp, Change: alert.StreamStarted, Message: "OBS started streaming"})
+	w.Close()
+
+	payloads := r.received()
+	if len(payloads) != 2 {
+		t.Fatalf("Expected 2 payloads, got %v", payloads)
+	}
+
+	a := payloads[0]
+	if a["type"] != "alert" || a["severity"] != "critical" || a["instance"] != "backup" || a["timestamp"] != "2025-12-23T15:01:25Z" {
+		t.Errorf("Unexpected alert payload %v", a)
+	}
+	if a["message"] != "Alert backup: high-rtt firing: obs_rtt_ms 154.2 > 150" {
+		t.Errorf("Unexpected alert message %v", a["message"])
+	}
+	fields := a["alert"].(map[string]any)
+	if fields["rule"] != "high-rtt" || fields["value"] != 154.2 || fields["state"] != "firing" {
+		t.Errorf("Unexpected alert fields %v", fields)
+	}
+
+	s := payloads[1]
+	if s["type"] != "stream_started" || s["severity"] != "info" || s["message"] != "OBS started streaming" {
+		t.Errorf("Unexpected state payload %v", s)
+	}
+	if _, ok := s["alert"]; ok {
+		t.Errorf("Expected no alert fields for a state change, got %v", s)
+	}
+}
+
+func TestWebhook_ChatFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		field  string
+	}{
+		{FormatSlack, "text"},
+		{FormatDiscord, "content"},
+		{FormatTeams, "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			r := newReceiver(t)
+			w := newWebhook(t, Endpoint{URL: r.server.URL, Format: tt.format})
+
+			w.StateChanged(alert.StateChange{Change: alert.Disconnected, Message: "OBS connection lost"})
+			w.Close()
+
+			payloads := r.received()
+			if len(payloads) != 1 || len(payloads[0]) != 1 || payloads[0][tt.field] != "[warning] OBS connection lost" {
+				t.Errorf("Expected a single %s field, got %v", tt.field, payloads)
+			}
+		})
+	}
+}
+
+func TestWebhook_Retries(t *testing.T) {
+	r := newReceiver(t, http.StatusBadGateway, http.StatusTooManyRequests)
+	w := newWebhook(t, Endpoint{URL: r.server.URL, Retries: 2})
+
+	w.Notify(firing)
+	r.wait(t, 3)
+	w.Close()
+
+	if payloads := r.received(); len(payloads) != 3 {
+		t.Errorf("Expected 2 retries, got %d requests", len(payloads))
+	}
+}
+
+func TestWebhook_NoRetryOnClientError(t *testing.T) {
+	r := newReceiver(t, http.StatusBadRequest)
+	w := newWebhook(t, Endpoint{URL: r.server.URL, Retries: 3})
+
+	w.Notify(firing)
+	w.Close()
+
+	if payloads := r.received(); len(payloads) != 1 {
+		t.Errorf("Expected no retries of a rejected payload, got %d requests", len(payloads))
+	}
+}
+
+func TestWebhook_RetriesGiveUp(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	w := newWebhook(t, Endpoint{URL: r.server.URL, Retries: 1})
+
+	w.Notify(firing)
+	r.wait(t, 2)
+	w.Close()
+
+	if payloads := r.received(); len(payloads) != 2 {
+		t.Errorf("Expected a single retry, got %d requests", len(payloads))
+	}
+}
+
+func TestWebhook_RateLimit(t *testing.T) {
+	r := newReceiver(t)
+	w := newWebhook(t, Endpoint{URL: r.server.URL, Format: FormatSlack, RateLimit: 2})
+
+	for i := 0; i < 5; i++ {
+		if err := w.StateChanged(alert.StateChange{Change: alert.Reconnected, Message: "Reconnected to OBS"}); err != nil {
+			t.Fatalf("StateChanged failed: %v", err)
+		}
+	}
+
+	// Once the minute is over, the next message reports the dropped messages
+	w.mu.Lock()
+	for i := range w.sent {
+		w.sent[i] = w.sent[i].Add(-time.Minute)
+	}
+	w.mu.Unlock()
+	w.StateChanged(alert.StateChange{Change: alert.Disconnected, Message: "OBS connection lost"})
+	w.Close()
+
+	payloads := r.received()
+	if len(payloads) != 3 {
+		t.Fatalf("Expected 3 payloads within the rate limit, got %v", payloads)
+	}
+	if text := payloads[2]["text"]; text != "[warning] OBS connection lost (3 earlier messages dropped by the rate limit)" {
+		t.Errorf("Expected the dropped messages to be reported, got %v", text)
+	}
+}
+
+func TestWebhook_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	w := newWebhook(t, Endpoint{URL: server.URL, Timeout: 50 * time.Millisecond})
+	w.Notify(firing)
+
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the request to time out")
+	}
+}
+
+func TestWebhook_CloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var logs strings.Builder
+	w, err := New(Endpoint{URL: server.URL, Timeout: time.Minute, RateLimit: DefaultRateLimit}, slog.New(slog.NewTextHandler(&logs, nil)))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	w.closeTimeout = 100 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		w.Notify(firing)
+	}
+
+	start := time.Now()
+	w.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected Close to give up after its timeout, took %v", elapsed)
+	}
+	if !strings.Contains(logs.String(), "Dropped 3 webhook messages") {
+		t.Errorf("Expected the dropped messages to be logged, got %q", logs.String())
+	}
+}
+
+func TestWebhook_Closed(t *testing.T) {
+	w := newWebhook(t, Endpoint{URL: "http://localhost:1"})
+	w.Close()
+
+	if err := w.Notify(firing); err == nil {
+		t.Error("Expected an error after Close")
+	}
+}
+
+func TestEndpoint_Validate(t *testing.T) {
+	err := Endpoint{URL: "hooks.slack.com/services/T0", Format: "irc", Retries: -1}.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, message := range []string{"invalid webhook URL", `unknown webhook format "irc"`, "timeout must be positive", "retries must not be negative", "rate limit must be positive"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q in %v", message, err)
+		}
+	}
+
+	valid := Endpoint{URL: "https://hooks.slack.com/services/T0", Format: FormatSlack, Timeout: DefaultTimeout, Retries: DefaultRetries, RateLimit: DefaultRateLimit}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid endpoint, got %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	if got := redact("https://hooks.slack.com/services/T0/B0/secret"); got != "https://hooks.slack.com" {
+		t.Errorf("Expected the path to be hidden, got %s", got)
+	}
+}
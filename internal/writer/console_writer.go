@@ -2,17 +2,39 @@ package writer
 
 import (
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
+
+const (
+	consoleTimestampWidth = 25
+	consoleMinColumnWidth = 10
 )
 
 // ConsoleWriter handles writing metrics to the console
 type ConsoleWriter struct {
+	columns []metric.Descriptor
+	// instanceWidth is the width of the instance column, 0 when there is none
+	instanceWidth int
 	headerPrinted bool
 }
 
-// NewConsoleWriter creates a new console writer
-func NewConsoleWriter() *ConsoleWriter {
+// NewConsoleWriter creates a new console writer for the given columns.
+// When instances are given, every row starts with the name of its OBS instance.
+func NewConsoleWriter(columns []metric.Descriptor, instances []string) *ConsoleWriter {
+	instanceWidth := 0
+	if len(instances) > 0 {
+		instanceWidth = len("instance")
+		for _, name := range instances {
+			instanceWidth = max(instanceWidth, len(name))
+		}
+	}
+
 	return &ConsoleWriter{
+		columns:       columns,
+		instanceWidth: instanceWidth,
 		headerPrinted: false,
 	}
 }
@@ -21,64 +43,45 @@ func NewConsoleWriter() *ConsoleWriter {
 func (cw *ConsoleWriter) WriteMetrics(data MetricsData) error {
 	// Print header on first call
 	if !cw.headerPrinted {
-		fmt.Println("timestamp                 | obs_rtt_ms | google_rtt_ms | stream_active | output_bytes | output_skipped_frames | output_frames | obs_cpu_% | obs_mem_mb | sys_cpu_% | sys_mem_% | errors")
-		fmt.Println("--------------------------|------------|---------------|---------------|--------------|-----------------------|---------------|-----------|------------|-----------|-----------|--------")
-		cw.headerPrinted = true
-	}
-
-	obsRttMs := "         -"
-	if data.ObsPingError == nil && data.ObsRTT > 0 {
-		obsRttMs = fmt.Sprintf("%10.2f", float64(data.ObsRTT.Microseconds())/1000.0)
-	}
-
-	googleRttMs := "            -"
-	if data.GooglePingError == nil && data.GoogleRTT > 0 {
-		googleRttMs = fmt.Sprintf("%13.2f", float64(data.GoogleRTT.Microseconds())/1000.0)
-	}
-
-	errors := ""
-	if data.ObsPingError != nil {
-		errors = fmt.Sprintf("obs_ping: %v", data.ObsPingError)
-	}
-	if data.GooglePingError != nil {
-		if errors != "" {
-			errors += "; "
+		header := []string{fmt.Sprintf("%-*s", consoleTimestampWidth, "timestamp")}
+		separator := []string{strings.Repeat("-", consoleTimestampWidth)}
+		if cw.instanceWidth > 0 {
+			header = append(header, fmt.Sprintf("%-*s", cw.instanceWidth, "instance"))
+			separator = append(separator, strings.Repeat("-", cw.instanceWidth))
 		}
-		errors += fmt.Sprintf("google_ping: %v", data.GooglePingError)
-	}
-	if data.StreamError != nil {
-		if errors != "" {
-			errors += "; "
+		for _, column := range cw.columns {
+			header = append(header, fmt.Sprintf("%-*s", consoleWidth(column), column.Name))
+			separator = append(separator, strings.Repeat("-", consoleWidth(column)))
 		}
-		errors += fmt.Sprintf("stream: %v", data.StreamError)
+		header = append(header, fmt.Sprintf("%-*s", consoleMinColumnWidth, "events"))
+		separator = append(separator, strings.Repeat("-", consoleMinColumnWidth))
+		fmt.Println(strings.Join(header, " | ") + " | errors")
+		fmt.Println(strings.Join(separator, "-|-") + "-|--------")
+		cw.headerPrinted = true
 	}
-	if data.ObsStatsError != nil {
-		if errors != "" {
-			errors += "; "
-		}
-		errors += fmt.Sprintf("obs_stats: %v", data.ObsStatsError)
+
+	values := []string{fmt.Sprintf("%*s", consoleTimestampWidth, data.Timestamp.Format(time.RFC3339))}
+	if cw.instanceWidth > 0 {
+		values = append(values, fmt.Sprintf("%-*s", cw.instanceWidth, data.Instance))
 	}
-	if data.SystemMetricsError != nil {
-		if errors != "" {
-			errors += "; "
+	for _, column := range cw.columns {
+		value := data.sampleFor(column).Format()
+		if value == "" {
+			value = "-"
 		}
-		errors += fmt.Sprintf("system: %v", data.SystemMetricsError)
+		values = append(values, fmt.Sprintf("%*s", consoleWidth(column), value))
 	}
+	values = append(values, fmt.Sprintf("%-*s", consoleMinColumnWidth, data.eventsText()))
+	fmt.Println(strings.Join(values, " | ") + " | " + data.errorsText())
 
-	fmt.Printf("%25s | %10s | %13s | %13t | %12.0f | %21.0f | %13.0f | %9.1f | %10.0f | %9.1f | %9.1f | %s\n",
-		data.Timestamp.Format(time.RFC3339),
-		obsRttMs,
-		googleRttMs,
-		data.StreamActive,
-		data.OutputBytes,
-		data.OutputSkippedFrames,
-		data.OutputFrames,
-		data.ObsCpuUsage,
-		data.ObsMemoryUsage,
-		data.SystemCpuUsage,
-		data.SystemMemoryUsage,
-		errors,
-	)
+	return nil
+}
+
+func consoleWidth(column metric.Descriptor) int {
+	return max(len(column.Name), consoleMinColumnWidth)
+}
 
+// Close is a no-op, the console is not owned by the writer
+func (cw *ConsoleWriter) Close() error {
 	return nil
 }
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
 )
 
 func TestConsoleWriter_WriteMetrics_HighRTT(t *testing.T) {
@@ -16,20 +18,16 @@ func TestConsoleWriter_WriteMetrics_HighRTT(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	cw := NewConsoleWriter()
+	cw := NewConsoleWriter(testColumns, nil)
 
 	// Test with 1001ms RTT (1001000 microseconds)
-	data := MetricsData{
-		Timestamp:           time.Date(2025, 12, 16, 10, 0, 0, 0, time.UTC),
-		ObsRTT:              1001 * time.Millisecond,
-		ObsPingError:        nil,
-		GoogleRTT:           25 * time.Millisecond,
-		GooglePingError:     nil,
-		StreamActive:        true,
-		OutputBytes:         123456.0,
-		OutputSkippedFrames: 10.0,
-		StreamError:         nil,
-	}
+	data := testRow(time.Date(2025, 12, 16, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"obs_rtt_ms":            1001,
+		"google_rtt_ms":         25,
+		"stream_active":         1,
+		"output_bytes":          123456.0,
+		"output_skipped_frames": 10.0,
+	})
 
 	err := cw.WriteMetrics(data)
 	if err != nil {
@@ -94,16 +92,15 @@ func TestConsoleWriter_WriteMetrics_NormalRTT(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	cw := NewConsoleWriter()
+	cw := NewConsoleWriter(testColumns, nil)
 
-	data := MetricsData{
-		Timestamp:           time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:              50 * time.Millisecond,
-		GoogleRTT:           25 * time.Millisecond,
-		StreamActive:        true,
-		OutputBytes:         1024.0,
-		OutputSkippedFrames: 5.0,
-	}
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"obs_rtt_ms":            50,
+		"google_rtt_ms":         25,
+		"stream_active":         1,
+		"output_bytes":          1024.0,
+		"output_skipped_frames": 5.0,
+	})
 
 	err := cw.WriteMetrics(data)
 	if err != nil {
@@ -127,16 +124,13 @@ func TestConsoleWriter_WriteMetrics_ZeroValues(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	cw := NewConsoleWriter()
+	cw := NewConsoleWriter(testColumns, nil)
 
-	data := MetricsData{
-		Timestamp:           time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:              0,
-		GoogleRTT:           0,
-		StreamActive:        false,
-		OutputBytes:         0,
-		OutputSkippedFrames: 0,
-	}
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"stream_active":         0,
+		"output_bytes":          0,
+		"output_skipped_frames": 0,
+	})
 
 	err := cw.WriteMetrics(data)
 	if err != nil {
@@ -161,19 +155,17 @@ func TestConsoleWriter_WriteMetrics_WithErrors(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	cw := NewConsoleWriter()
+	cw := NewConsoleWriter(testColumns, nil)
 
 	obsErr := fmt.Errorf("obs ping error")
 	googleErr := fmt.Errorf("google ping error")
 
-	data := MetricsData{
-		Timestamp:       time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:          0,
-		ObsPingError:    obsErr,
-		GoogleRTT:       0,
-		GooglePingError: googleErr,
-		StreamActive:    false,
-	}
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"stream_active": 0,
+	},
+		metric.CollectorError{Collector: "obs_ping", Err: obsErr},
+		metric.CollectorError{Collector: "google_ping", Err: googleErr},
+	)
 
 	err := cw.WriteMetrics(data)
 	if err != nil {
@@ -197,14 +189,13 @@ func TestConsoleWriter_WriteMetrics_InactiveStream(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	cw := NewConsoleWriter()
+	cw := NewConsoleWriter(testColumns, nil)
 
-	data := MetricsData{
-		Timestamp:    time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:       50 * time.Millisecond,
-		GoogleRTT:    25 * time.Millisecond,
-		StreamActive: false,
-	}
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"obs_rtt_ms":    50,
+		"google_rtt_ms": 25,
+		"stream_active": 0,
+	})
 
 	err := cw.WriteMetrics(data)
 	if err != nil {
@@ -228,27 +219,12 @@ func TestConsoleWriter_WriteMetrics_FormattingConsistency(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	cw := NewConsoleWriter()
+	cw := NewConsoleWriter(testColumns, nil)
 
 	testCases := []MetricsData{
-		{
-			Timestamp:    time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-			ObsRTT:       50 * time.Millisecond,
-			GoogleRTT:    25 * time.Millisecond,
-			StreamActive: true,
-		},
-		{
-			Timestamp:    time.Date(2025, 12, 23, 10, 1, 0, 0, time.UTC),
-			ObsRTT:       100 * time.Millisecond,
-			GoogleRTT:    50 * time.Millisecond,
-			StreamActive: true,
-		},
-		{
-			Timestamp:    time.Date(2025, 12, 23, 10, 2, 0, 0, time.UTC),
-			ObsRTT:       75 * time.Millisecond,
-			GoogleRTT:    30 * time.Millisecond,
-			StreamActive: false,
-		},
+		testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{"obs_rtt_ms": 50, "google_rtt_ms": 25, "stream_active": 1}),
+		testRow(time.Date(2025, 12, 23, 10, 1, 0, 0, time.UTC), map[string]float64{"obs_rtt_ms": 100, "google_rtt_ms": 50, "stream_active": 1}),
+		testRow(time.Date(2025, 12, 23, 10, 2, 0, 0, time.UTC), map[string]float64{"obs_rtt_ms": 75, "google_rtt_ms": 30, "stream_active": 0}),
 	}
 
 	for _, data := range testCases {
@@ -276,7 +252,7 @@ func TestConsoleWriter_WriteMetrics_FormattingConsistency(t *testing.T) {
 }
 
 func TestConsoleWriter_NewConsoleWriter(t *testing.T) {
-	cw := NewConsoleWriter()
+	cw := NewConsoleWriter(testColumns, nil)
 
 	if cw == nil {
 		t.Fatal("NewConsoleWriter returned nil")
@@ -288,21 +264,20 @@ func TestConsoleWriter_WriteMetrics_AllMetrics(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	cw := NewConsoleWriter()
-
-	data := MetricsData{
-		Timestamp:           time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:              50 * time.Millisecond,
-		GoogleRTT:           25 * time.Millisecond,
-		StreamActive:        true,
-		OutputBytes:         1024.0,
-		OutputSkippedFrames: 5.0,
-		OutputFrames:        100.0,
-		ObsCpuUsage:         15.5,
-		ObsMemoryUsage:      512.0,
-		SystemCpuUsage:      45.2,
-		SystemMemoryUsage:   60.0,
-	}
+	cw := NewConsoleWriter(testColumns, nil)
+
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"obs_rtt_ms":            50,
+		"google_rtt_ms":         25,
+		"stream_active":         1,
+		"output_bytes":          1024.0,
+		"output_skipped_frames": 5.0,
+		"output_frames":         100.0,
+		"obs_cpu_percent":       15.5,
+		"obs_memory_mb":         512.0,
+		"system_cpu_percent":    45.2,
+		"system_memory_percent": 60.0,
+	})
 
 	err := cw.WriteMetrics(data)
 	if err != nil {
@@ -326,3 +301,59 @@ func TestConsoleWriter_WriteMetrics_AllMetrics(t *testing.T) {
 		t.Error("Expected to find OBS RTT in output")
 	}
 }
+
+func TestConsoleWriter_WriteMetrics_MissingValue(t *testing.T) {
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	columns := []metric.Descriptor{{Name: "custom_value", Precision: 1}}
+	cw := NewConsoleWriter(columns, nil)
+
+	err := cw.WriteMetrics(MetricsData{Timestamp: time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "custom_value") {
+		t.Errorf("Expected declared column in header, got: %s", lines[0])
+	}
+	if !strings.HasSuffix(strings.TrimSpace(lines[2]), "- |            |") {
+		t.Errorf("Expected missing value to be rendered as -, got: %s", lines[2])
+	}
+}
+
+func TestConsoleWriter_InstanceColumn(t *testing.T) {
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	columns := []metric.Descriptor{{Name: "custom_value"}}
+	cw := NewConsoleWriter(columns, []string{"main", "iso_recorder"})
+
+	err := cw.WriteMetrics(MetricsData{Timestamp: time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), Instance: "main"})
+	if err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "| instance     | custom_value") {
+		t.Errorf("Expected instance column as wide as the longest name in header, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[2], "| main         |") {
+		t.Errorf("Expected instance name in row, got: %s", lines[2])
+	}
+}
@@ -1,23 +1,44 @@
 package writer
 
 import (
+	"bufio"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"sync"
 	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
 )
 
 // CSVWriter handles writing metrics to a CSV file
 type CSVWriter struct {
-	file   *os.File
-	writer *csv.Writer
-	mu     sync.Mutex
+	filename  string
+	file      *os.File
+	writer    *csv.Writer
+	columns   []metric.Descriptor
+	instanced bool
+	mu        sync.Mutex
+}
+
+// NewCSVWriter creates a new CSV writer and writes the header with the given columns.
+// When instances are given, every row starts with the name of its OBS instance.
+func NewCSVWriter(filename, obsVersion, streamDomain string, columns []metric.Descriptor, instances []string) (*CSVWriter, error) {
+	return newCSVWriter(filename, csvMetadata(obsVersion, streamDomain), columns, instances)
 }
 
-// NewCSVWriter creates a new CSV writer and writes the header
-func NewCSVWriter(filename, obsVersion, streamDomain string) (*CSVWriter, error) {
+// csvMetadata returns the header information of the first line
+func csvMetadata(obsVersion, streamDomain string) []string {
+	return []string{
+		fmt.Sprintf("OBS Studio version: %s", obsVersion),
+		fmt.Sprintf("Stream domain: %s", streamDomain),
+		fmt.Sprintf("OS: %s", runtime.GOOS),
+	}
+}
+
+func newCSVWriter(filename string, metadata []string, columns []metric.Descriptor, instances []string) (*CSVWriter, error) {
 	file, err := os.Create(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CSV file: %w", err)
@@ -26,31 +47,20 @@ func NewCSVWriter(filename, obsVersion, streamDomain string) (*CSVWriter, error)
 	writer := csv.NewWriter(file)
 
 	// Write header information
-	headerInfo := []string{
-		fmt.Sprintf("OBS Studio version: %s", obsVersion),
-		fmt.Sprintf("Stream domain: %s", streamDomain),
-		fmt.Sprintf("OS: %s", runtime.GOOS),
-	}
-	if err := writer.Write(headerInfo); err != nil {
+	if err := writer.Write(metadata); err != nil {
 		file.Close()
 		return nil, fmt.Errorf("failed to write CSV header info: %w", err)
 	}
 
 	// Write column header
-	header := []string{
-		"timestamp",
-		"obs_rtt_ms",
-		"google_rtt_ms",
-		"stream_active",
-		"output_bytes",
-		"output_skipped_frames",
-		"output_frames",
-		"obs_cpu_percent",
-		"obs_memory_mb",
-		"system_cpu_percent",
-		"system_memory_percent",
-		"errors",
+	header := []string{"timestamp"}
+	if len(instances) > 0 {
+		header = append(header, "instance")
+	}
+	for _, column := range columns {
+		header = append(header, column.Name)
 	}
+	header = append(header, "events", "errors")
 	if err := writer.Write(header); err != nil {
 		file.Close()
 		return nil, fmt.Errorf("failed to write CSV header: %w", err)
@@ -58,8 +68,11 @@ func NewCSVWriter(filename, obsVersion, streamDomain string) (*CSVWriter, error)
 	writer.Flush()
 
 	return &CSVWriter{
-		file:   file,
-		writer: writer,
+		filename:  filename,
+		file:      file,
+		writer:    writer,
+		columns:   columns,
+		instanced: len(instances) > 0,
 	}, nil
 }
 
@@ -68,59 +81,14 @@ func (cw *CSVWriter) WriteMetrics(data MetricsData) error {
 	cw.mu.Lock()
 	defer cw.mu.Unlock()
 
-	obsRttMs := ""
-	if data.ObsPingError == nil && data.ObsRTT > 0 {
-		obsRttMs = fmt.Sprintf("%.2f", float64(data.ObsRTT.Microseconds())/1000.0)
+	row := []string{data.Timestamp.Format(time.RFC3339)}
+	if cw.instanced {
+		row = append(row, data.Instance)
 	}
-
-	googleRttMs := ""
-	if data.GooglePingError == nil && data.GoogleRTT > 0 {
-		googleRttMs = fmt.Sprintf("%.2f", float64(data.GoogleRTT.Microseconds())/1000.0)
-	}
-
-	errors := ""
-	if data.ObsPingError != nil {
-		errors = fmt.Sprintf("obs_ping: %v", data.ObsPingError)
-	}
-	if data.GooglePingError != nil {
-		if errors != "" {
-			errors += "; "
-		}
-		errors += fmt.Sprintf("google_ping: %v", data.GooglePingError)
-	}
-	if data.StreamError != nil {
-		if errors != "" {
-			errors += "; "
-		}
-		errors += fmt.Sprintf("stream: %v", data.StreamError)
-	}
-	if data.ObsStatsError != nil {
-		if errors != "" {
-			errors += "; "
-		}
-		errors += fmt.Sprintf("obs_stats: %v", data.ObsStatsError)
-	}
-	if data.SystemMetricsError != nil {
-		if errors != "" {
-			errors += "; "
-		}
-		errors += fmt.Sprintf("system: %v", data.SystemMetricsError)
-	}
-
-	row := []string{
-		data.Timestamp.Format(time.RFC3339),
-		obsRttMs,
-		googleRttMs,
-		fmt.Sprintf("%t", data.StreamActive),
-		fmt.Sprintf("%.0f", data.OutputBytes),
-		fmt.Sprintf("%.0f", data.OutputSkippedFrames),
-		fmt.Sprintf("%.0f", data.OutputFrames),
-		fmt.Sprintf("%.2f", data.ObsCpuUsage),
-		fmt.Sprintf("%.2f", data.ObsMemoryUsage),
-		fmt.Sprintf("%.2f", data.SystemCpuUsage),
-		fmt.Sprintf("%.2f", data.SystemMemoryUsage),
-		errors,
+	for _, column := range cw.columns {
+		row = append(row, data.sampleFor(column).Format())
 	}
+	row = append(row, data.eventsText(), data.errorsText())
 
 	if err := cw.writer.Write(row); err != nil {
 		return fmt.Errorf("failed to write CSV row: %w", err)
@@ -137,3 +105,59 @@ func (cw *CSVWriter) Close() error {
 	cw.writer.Flush()
 	return cw.file.Close()
 }
+
+// closeWithMetadata closes the CSV file and replaces its header information,
+// for information that is only known at the end, like when the stream stopped.
+// The rows are copied to a new file that replaces the old one once it is complete, so a failed write keeps the old file.
+func (cw *CSVWriter) closeWithMetadata(metadata []string) error {
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	// Close flushes the rows, a failed flush leaves the file as it is
+	if err := cw.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV file: %w", err)
+	}
+
+	tmp := cw.filename + ".tmp"
+	if err := copyWithMetadata(cw.filename, tmp, metadata); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write CSV header info: %w", err)
+	}
+	if err := os.Rename(tmp, cw.filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write CSV header info: %w", err)
+	}
+	return nil
+}
+
+// copyWithMetadata copies the CSV file src to dst, with metadata instead of its first line
+func copyWithMetadata(src, dst string, metadata []string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	reader := bufio.NewReader(in)
+	if _, err := reader.ReadBytes('\n'); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(out)
+	writer.Write(metadata)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
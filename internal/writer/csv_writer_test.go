@@ -7,13 +7,15 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
 )
 
 func TestCSVWriter_NewCSVWriter_CreatesFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
@@ -34,7 +36,7 @@ func TestCSVWriter_NewCSVWriter_WritesHeaders(t *testing.T) {
 	obsVersion := "30.0.0"
 	streamDomain := "live.twitch.tv"
 
-	cw, err := NewCSVWriter(filename, obsVersion, streamDomain)
+	cw, err := NewCSVWriter(filename, obsVersion, streamDomain, testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
@@ -62,24 +64,23 @@ func TestCSVWriter_WriteMetrics_SingleRow(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
 
-	data := MetricsData{
-		Timestamp:           time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:              50 * time.Millisecond,
-		GoogleRTT:           25 * time.Millisecond,
-		StreamActive:        true,
-		OutputBytes:         1024.0,
-		OutputSkippedFrames: 5.0,
-		OutputFrames:        100.0,
-		ObsCpuUsage:         15.5,
-		ObsMemoryUsage:      512.0,
-		SystemCpuUsage:      45.2,
-		SystemMemoryUsage:   60.0,
-	}
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"obs_rtt_ms":            50,
+		"google_rtt_ms":         25,
+		"stream_active":         1,
+		"output_bytes":          1024.0,
+		"output_skipped_frames": 5.0,
+		"output_frames":         100.0,
+		"obs_cpu_percent":       15.5,
+		"obs_memory_mb":         512.0,
+		"system_cpu_percent":    45.2,
+		"system_memory_percent": 60.0,
+	})
 
 	err = cw.WriteMetrics(data)
 	if err != nil {
@@ -113,17 +114,16 @@ func TestCSVWriter_WriteMetrics_MultipleRows(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
 
 	for i := 0; i < 3; i++ {
-		data := MetricsData{
-			Timestamp:    time.Date(2025, 12, 23, 10, i, 0, 0, time.UTC),
-			ObsRTT:       time.Duration(i*10) * time.Millisecond,
-			StreamActive: true,
-		}
+		data := testRow(time.Date(2025, 12, 23, 10, i, 0, 0, time.UTC), map[string]float64{
+			"obs_rtt_ms":    float64(i * 10),
+			"stream_active": 1,
+		})
 		err = cw.WriteMetrics(data)
 		if err != nil {
 			t.Fatalf("WriteMetrics failed on iteration %d: %v", i, err)
@@ -146,7 +146,7 @@ func TestCSVWriter_WriteMetrics_WithErrors(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
@@ -154,12 +154,12 @@ func TestCSVWriter_WriteMetrics_WithErrors(t *testing.T) {
 	obsErr := fmt.Errorf("obs ping failed")
 	streamErr := fmt.Errorf("stream error")
 
-	data := MetricsData{
-		Timestamp:    time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsPingError: obsErr,
-		StreamError:  streamErr,
-		StreamActive: false,
-	}
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"stream_active": 0,
+	},
+		metric.CollectorError{Collector: "obs_ping", Err: obsErr},
+		metric.CollectorError{Collector: "stream", Err: streamErr},
+	)
 
 	err = cw.WriteMetrics(data)
 	if err != nil {
@@ -181,10 +181,42 @@ func TestCSVWriter_WriteMetrics_WithErrors(t *testing.T) {
 	}
 }
 
+func TestCSVWriter_WriteMetrics_WithEvents(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.csv")
+
+	columns := []metric.Descriptor{{Name: "custom_value"}}
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", columns, nil)
+	if err != nil {
+		t.Fatalf("NewCSVWriter failed: %v", err)
+	}
+
+	data := MetricsData{
+		Timestamp: time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
+		Events: []Event{
+			{Type: "StreamStateChanged", Message: "stream started"},
+			{Type: "CurrentProgramSceneChanged", Message: "scene Live"},
+		},
+	}
+	if err := cw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	cw.Close()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if lines[2] != "2025-12-23T10:00:00Z,,stream started; scene Live," {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+}
+
 func TestCSVWriter_NewCSVWriter_InvalidPath(t *testing.T) {
 	filename := "/invalid/path/that/does/not/exist/test.csv"
 
-	_, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	_, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 
 	if err == nil {
 		t.Error("Expected error when creating file in invalid path")
@@ -195,15 +227,14 @@ func TestCSVWriter_Close_FlushesData(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
 
-	data := MetricsData{
-		Timestamp:    time.Now(),
-		StreamActive: true,
-	}
+	data := testRow(time.Now(), map[string]float64{
+		"stream_active": 1,
+	})
 	_ = cw.WriteMetrics(data)
 
 	err = cw.Close()
@@ -225,24 +256,21 @@ func TestCSVWriter_WriteMetrics_ZeroValues(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
 
-	data := MetricsData{
-		Timestamp:           time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:              0,
-		GoogleRTT:           0,
-		StreamActive:        false,
-		OutputBytes:         0,
-		OutputSkippedFrames: 0,
-		OutputFrames:        0,
-		ObsCpuUsage:         0,
-		ObsMemoryUsage:      0,
-		SystemCpuUsage:      0,
-		SystemMemoryUsage:   0,
-	}
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"stream_active":         0,
+		"output_bytes":          0,
+		"output_skipped_frames": 0,
+		"output_frames":         0,
+		"obs_cpu_percent":       0,
+		"obs_memory_mb":         0,
+		"system_cpu_percent":    0,
+		"system_memory_percent": 0,
+	})
 
 	err = cw.WriteMetrics(data)
 	if err != nil {
@@ -265,16 +293,14 @@ func TestCSVWriter_WriteMetrics_SpecialCharactersInError(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
 
-	data := MetricsData{
-		Timestamp:    time.Now(),
-		StreamError:  fmt.Errorf("error with \"quotes\" and, commas"),
-		StreamActive: false,
-	}
+	data := testRow(time.Now(), map[string]float64{
+		"stream_active": 0,
+	}, metric.CollectorError{Collector: "stream", Err: fmt.Errorf("error with \"quotes\" and, commas")})
 
 	err = cw.WriteMetrics(data)
 	if err != nil {
@@ -296,17 +322,16 @@ func TestCSVWriter_WriteMetrics_HighRTTValues(t *testing.T) {
 	tmpDir := t.TempDir()
 	filename := filepath.Join(tmpDir, "test.csv")
 
-	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv")
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", testColumns, nil)
 	if err != nil {
 		t.Fatalf("NewCSVWriter failed: %v", err)
 	}
 
-	data := MetricsData{
-		Timestamp:    time.Now(),
-		ObsRTT:       1500 * time.Millisecond,
-		GoogleRTT:    2000 * time.Millisecond,
-		StreamActive: true,
-	}
+	data := testRow(time.Now(), map[string]float64{
+		"obs_rtt_ms":    1500,
+		"google_rtt_ms": 2000,
+		"stream_active": 1,
+	})
 
 	err = cw.WriteMetrics(data)
 	if err != nil {
@@ -324,3 +349,108 @@ func TestCSVWriter_WriteMetrics_HighRTTValues(t *testing.T) {
 		t.Error("CSV should contain high RTT value in milliseconds")
 	}
 }
+
+func TestCSVWriter_RendersDeclaredColumns(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.csv")
+	columns := []metric.Descriptor{
+		{Name: "custom_ratio", Kind: metric.Gauge, Precision: 3},
+		{Name: "custom_flag", Kind: metric.Bool},
+		{Name: "custom_missing", Kind: metric.Gauge},
+	}
+
+	cw, err := NewCSVWriter(filename, "30.0.0", "live.twitch.tv", columns, nil)
+	if err != nil {
+		t.Fatalf("NewCSVWriter failed: %v", err)
+	}
+
+	data := MetricsData{
+		Timestamp: time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
+		Samples: []metric.Sample{
+			columns[1].BoolSample(true),
+			columns[0].Sample(0.5),
+		},
+	}
+	if err := cw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	cw.Close()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if lines[1] != "timestamp,custom_ratio,custom_flag,custom_missing,events,errors" {
+		t.Errorf("Unexpected header %q", lines[1])
+	}
+	if lines[2] != "2025-12-23T10:00:00Z,0.500,true,,," {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+}
+
+func TestCSVWriter_InstanceColumn(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.csv")
+	columns := []metric.Descriptor{{Name: "custom_value", Kind: metric.Gauge}}
+
+	cw, err := NewCSVWriter(filename, "main=30.0.0, backup=30.0.0", "main=a.example.com, backup=b.example.com", columns, []string{"main", "backup"})
+	if err != nil {
+		t.Fatalf("NewCSVWriter failed: %v", err)
+	}
+
+	timestamp := time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC)
+	for i, instance := range []string{"main", "backup"} {
+		data := MetricsData{
+			Timestamp: timestamp,
+			Instance:  instance,
+			Samples:   []metric.Sample{columns[0].Sample(float64(i))},
+		}
+		if err := cw.WriteMetrics(data); err != nil {
+			t.Fatalf("WriteMetrics failed: %v", err)
+		}
+	}
+	cw.Close()
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	expected := []string{
+		"timestamp,instance,custom_value,events,errors",
+		"2025-12-23T10:00:00Z,main,0,,",
+		"2025-12-23T10:00:00Z,backup,1,,",
+	}
+	for i, line := range expected {
+		if lines[i+1] != line {
+			t.Errorf("Expected line %q, got %q", line, lines[i+1])
+		}
+	}
+}
+
+func TestCSVWriter_CloseWithMetadata_KeepsFileOnFailure(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "session.csv")
+	cw, err := newCSVWriter(filename, []string{"Stream started: now"}, nil, nil)
+	if err != nil {
+		t.Fatalf("newCSVWriter failed: %v", err)
+	}
+	if err := cw.WriteMetrics(MetricsData{Timestamp: time.Now()}); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	// A directory in place of the temporary file makes the replacement fail
+	if err := os.Mkdir(filename+".tmp", 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	os.WriteFile(filepath.Join(filename+".tmp", "keep"), nil, 0o644)
+
+	if err := cw.closeWithMetadata([]string{"Stream started: now", "Stream stopped: later"}); err == nil {
+		t.Fatal("Expected the failed replacement to be reported")
+	}
+
+	records := readCSV(t, filename)
+	if len(records) != 3 || records[0][0] != "Stream started: now" || len(records[0]) != 1 {
+		t.Errorf("Expected the file to be kept as it was, got %v", records)
+	}
+}
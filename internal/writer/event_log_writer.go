@@ -0,0 +1,101 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is a state change reported by OBS, e.g. a scene change
+type Event struct {
+	Timestamp time.Time
+	// Instance is the name of the OBS instance the event belongs to, empty when a single instance is monitored
+	Instance string
+	// Type is the name of the OBS event, e.g. CurrentProgramSceneChanged
+	Type string
+	// Message describes the event in the events column, e.g. "scene Live"
+	Message string
+	// Data holds the fields of the OBS event
+	Data map[string]any
+}
+
+// EventWriter is implemented by writers that record the events of the OBS instances as they happen
+type EventWriter interface {
+	WriteEvent(event Event) error
+}
+
+// EventLogWriter writes one JSON object per OBS event, metrics rows are ignored
+type EventLogWriter struct {
+	file *os.File
+	out  io.Writer
+	mu   sync.Mutex
+}
+
+type eventLogLine struct {
+	Timestamp string         `json:"timestamp"`
+	Instance  string         `json:"instance,omitempty"`
+	Type      string         `json:"type"`
+	Message   string         `json:"message"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// NewEventLogWriter creates an event log for the given file, "-" writes to stdout
+func NewEventLogWriter(filename string) (*EventLogWriter, error) {
+	if filename == "-" {
+		return newEventLogWriter(os.Stdout, nil), nil
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event log: %w", err)
+	}
+	return newEventLogWriter(file, file), nil
+}
+
+func newEventLogWriter(out io.Writer, file *os.File) *EventLogWriter {
+	return &EventLogWriter{
+		file: file,
+		out:  out,
+	}
+}
+
+// WriteEvent writes a single event as a JSON object on its own line
+func (ew *EventLogWriter) WriteEvent(event Event) error {
+	line, err := json.Marshal(eventLogLine{
+		Timestamp: event.Timestamp.Format(time.RFC3339Nano),
+		Instance:  event.Instance,
+		Type:      event.Type,
+		Message:   event.Message,
+		Data:      event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+
+	if _, err := ew.out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
+
+// WriteMetrics is a no-op, the events of a row are written as they happen
+func (ew *EventLogWriter) WriteMetrics(data MetricsData) error {
+	return nil
+}
+
+// Close closes the event log, stdout is left open
+func (ew *EventLogWriter) Close() error {
+	if ew.file == nil {
+		return nil
+	}
+
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	return ew.file.Close()
+}
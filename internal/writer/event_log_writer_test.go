@@ -0,0 +1,48 @@
+package writer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEventLogWriter_WriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	ew := newEventLogWriter(&buf, nil)
+
+	err := ew.WriteEvent(Event{
+		Timestamp: time.Date(2025, 12, 23, 15, 1, 25, 0, time.UTC),
+		Instance:  "backup",
+		Type:      "CurrentProgramSceneChanged",
+		Message:   "scene BRB",
+		Data:      map[string]any{"sceneName": "BRB"},
+	})
+	if err != nil {
+		t.Fatalf("WriteEvent failed: %v", err)
+	}
+
+	expected := `{"timestamp":"2025-12-23T15:01:25Z","instance":"backup","type":"CurrentProgramSceneChanged","message":"scene BRB","data":{"sceneName":"BRB"}}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+}
+
+func TestEventLogWriter_IgnoresMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	ew := newEventLogWriter(&buf, nil)
+
+	data := testRow(time.Now(), nil)
+	data.Events = []Event{{Type: "StreamStateChanged", Message: "stream started"}}
+	if err := ew.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected metrics rows to be ignored, got %s", buf.String())
+	}
+}
+
+func TestEventLogWriter_InvalidPath(t *testing.T) {
+	if _, err := NewEventLogWriter("/invalid/path/that/does/not/exist/events.jsonl"); err == nil {
+		t.Error("Expected error for invalid path")
+	}
+}
@@ -0,0 +1,134 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
+
+// JSONLWriter writes one JSON object per metrics row
+type JSONLWriter struct {
+	file *os.File
+	out  io.Writer
+	mu   sync.Mutex
+}
+
+type jsonlEvent struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type jsonlError struct {
+	Collector string `json:"collector"`
+	Message   string `json:"message"`
+}
+
+// NewJSONLWriter creates a JSON Lines writer for the given file, "-" writes to stdout
+func NewJSONLWriter(filename string) (*JSONLWriter, error) {
+	if filename == "-" {
+		return newJSONLWriter(os.Stdout, nil), nil
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JSONL file: %w", err)
+	}
+	return newJSONLWriter(file, file), nil
+}
+
+func newJSONLWriter(out io.Writer, file *os.File) *JSONLWriter {
+	return &JSONLWriter{
+		file: file,
+		out:  out,
+	}
+}
+
+// WriteMetrics writes a single metrics data row as a JSON object on its own line.
+// The fields follow the column order, missing values are null.
+func (jw *JSONLWriter) WriteMetrics(data MetricsData) error {
+	var line bytes.Buffer
+
+	line.WriteString(`{"timestamp":`)
+	if err := writeJSON(&line, data.Timestamp.Format(time.RFC3339Nano)); err != nil {
+		return err
+	}
+	if data.Instance != "" {
+		line.WriteString(`,"instance":`)
+		if err := writeJSON(&line, data.Instance); err != nil {
+			return err
+		}
+	}
+	for _, s := range data.Samples {
+		line.WriteByte(',')
+		if err := writeJSON(&line, s.Name); err != nil {
+			return err
+		}
+		line.WriteByte(':')
+		if err := writeJSON(&line, jsonValue(s)); err != nil {
+			return err
+		}
+	}
+
+	events := []jsonlEvent{}
+	for _, e := range data.Events {
+		events = append(events, jsonlEvent{Type: e.Type, Message: e.Message})
+	}
+	line.WriteString(`,"events":`)
+	if err := writeJSON(&line, events); err != nil {
+		return err
+	}
+
+	errs := []jsonlError{}
+	for _, e := range data.Errors {
+		errs = append(errs, jsonlError{Collector: e.Collector, Message: e.Err.Error()})
+	}
+	line.WriteString(`,"errors":`)
+	if err := writeJSON(&line, errs); err != nil {
+		return err
+	}
+	line.WriteString("}\n")
+
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+
+	if _, err := jw.out.Write(line.Bytes()); err != nil {
+		return fmt.Errorf("failed to write JSONL row: %w", err)
+	}
+	return nil
+}
+
+// Close closes the JSONL file, stdout is left open
+func (jw *JSONLWriter) Close() error {
+	if jw.file == nil {
+		return nil
+	}
+
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	return jw.file.Close()
+}
+
+func writeJSON(buf *bytes.Buffer, v any) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSONL value: %w", err)
+	}
+	buf.Write(encoded)
+	return nil
+}
+
+func jsonValue(s metric.Sample) any {
+	if !s.Valid {
+		return nil
+	}
+	if s.Kind == metric.Bool {
+		return s.Value != 0
+	}
+	return s.Value
+}
@@ -0,0 +1,235 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
+
+func decodeJSONLines(t *testing.T, content string) []map[string]any {
+	t.Helper()
+
+	records := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Line is not valid JSON: %v: %s", err, line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestJSONLWriter_WriteMetrics_TypedFields(t *testing.T) {
+	var buf bytes.Buffer
+	jw := newJSONLWriter(&buf, nil)
+
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"obs_connected":         1,
+		"obs_rtt_ms":            50,
+		"google_rtt_ms":         25,
+		"stream_active":         1,
+		"output_bytes":          1024.0,
+		"output_skipped_frames": 5.0,
+		"output_frames":         100.0,
+		"obs_cpu_percent":       15.5,
+		"obs_memory_mb":         512.0,
+		"system_cpu_percent":    45.2,
+		"system_memory_percent": 60.0,
+	})
+	if err := jw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	records := decodeJSONLines(t, buf.String())
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+
+	if record["timestamp"] != "2025-12-23T10:00:00Z" {
+		t.Errorf("Unexpected timestamp %v", record["timestamp"])
+	}
+	if record["obs_rtt_ms"] != 50.0 {
+		t.Errorf("Expected obs_rtt_ms to be the number 50, got %v", record["obs_rtt_ms"])
+	}
+	if record["stream_active"] != true {
+		t.Errorf("Expected stream_active to be the boolean true, got %v", record["stream_active"])
+	}
+	if record["output_bytes"] != 1024.0 {
+		t.Errorf("Expected output_bytes to be the number 1024, got %v", record["output_bytes"])
+	}
+	if record["obs_cpu_percent"] != 15.5 {
+		t.Errorf("Expected obs_cpu_percent to be 15.5, got %v", record["obs_cpu_percent"])
+	}
+	if errs, ok := record["errors"].([]any); !ok || len(errs) != 0 {
+		t.Errorf("Expected an empty errors array, got %v", record["errors"])
+	}
+}
+
+func TestJSONLWriter_WriteMetrics_MissingRTTIsNull(t *testing.T) {
+	var buf bytes.Buffer
+	jw := newJSONLWriter(&buf, nil)
+
+	data := testRow(time.Now(), nil, metric.CollectorError{Collector: "obs_ping", Err: errors.New("no response received")})
+	if err := jw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	record := decodeJSONLines(t, buf.String())[0]
+
+	for _, field := range []string{"obs_rtt_ms", "google_rtt_ms"} {
+		value, ok := record[field]
+		if !ok {
+			t.Errorf("Expected field %s to be present", field)
+		}
+		if value != nil {
+			t.Errorf("Expected %s to be null, got %v", field, value)
+		}
+	}
+}
+
+func TestJSONLWriter_WriteMetrics_StructuredErrors(t *testing.T) {
+	var buf bytes.Buffer
+	jw := newJSONLWriter(&buf, nil)
+
+	data := testRow(time.Now(), nil,
+		metric.CollectorError{Collector: "obs_ping", Err: errors.New("no response received")},
+		metric.CollectorError{Collector: "stream", Err: errors.New("error with \"quotes\"; and semicolons")},
+	)
+	if err := jw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	record := decodeJSONLines(t, buf.String())[0]
+
+	errs, ok := record["errors"].([]any)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %v", record["errors"])
+	}
+
+	first := errs[0].(map[string]any)
+	if first["collector"] != "obs_ping" || first["message"] != "no response received" {
+		t.Errorf("Unexpected first error %v", first)
+	}
+	second := errs[1].(map[string]any)
+	if second["collector"] != "stream" || second["message"] != "error with \"quotes\"; and semicolons" {
+		t.Errorf("Unexpected second error %v", second)
+	}
+}
+
+func TestJSONLWriter_WriteMetrics_Events(t *testing.T) {
+	var buf bytes.Buffer
+	jw := newJSONLWriter(&buf, nil)
+
+	data := testRow(time.Now(), nil)
+	data.Events = []Event{
+		{Type: "CurrentProgramSceneChanged", Message: "scene BRB", Data: map[string]any{"sceneName": "BRB"}},
+		{Type: "InputMuteStateChanged", Message: "Mic/Aux muted"},
+	}
+	if err := jw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	record := decodeJSONLines(t, buf.String())[0]
+
+	events, ok := record["events"].([]any)
+	if !ok || len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %v", record["events"])
+	}
+	first := events[0].(map[string]any)
+	if first["type"] != "CurrentProgramSceneChanged" || first["message"] != "scene BRB" {
+		t.Errorf("Unexpected first event %v", first)
+	}
+	if _, ok := first["data"]; ok {
+		t.Errorf("Expected the event data to be left to the event log, got %v", first)
+	}
+}
+
+func TestJSONLWriter_File_OneObjectPerLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "metrics.jsonl")
+
+	jw, err := NewJSONLWriter(filename)
+	if err != nil {
+		t.Fatalf("NewJSONLWriter failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := jw.WriteMetrics(MetricsData{Timestamp: time.Date(2025, 12, 23, 10, i, 0, 0, time.UTC)}); err != nil {
+			t.Fatalf("WriteMetrics failed: %v", err)
+		}
+	}
+	if err := jw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read JSONL file: %v", err)
+	}
+
+	if records := decodeJSONLines(t, string(content)); len(records) != 3 {
+		t.Errorf("Expected 3 records without a metadata line, got %d", len(records))
+	}
+}
+
+func TestJSONLWriter_Stdout_CloseLeavesStdoutOpen(t *testing.T) {
+	jw, err := NewJSONLWriter("-")
+	if err != nil {
+		t.Fatalf("NewJSONLWriter failed: %v", err)
+	}
+
+	if err := jw.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		t.Errorf("Stdout should still be usable after Close: %v", err)
+	}
+}
+
+func TestJSONLWriter_InvalidPath(t *testing.T) {
+	_, err := NewJSONLWriter("/invalid/path/that/does/not/exist/metrics.jsonl")
+	if err == nil {
+		t.Error("Expected error when creating file in invalid path")
+	}
+}
+
+func TestJSONLWriter_WriteMetrics_ColumnOrder(t *testing.T) {
+	var buf bytes.Buffer
+	jw := newJSONLWriter(&buf, nil)
+
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{"obs_connected": 1})
+	data.Samples = data.Samples[:2]
+	if err := jw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	expected := `{"timestamp":"2025-12-23T10:00:00Z","obs_connected":true,"obs_rtt_ms":null,"events":[],"errors":[]}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+}
+
+func TestJSONLWriter_WriteMetrics_Instance(t *testing.T) {
+	var buf bytes.Buffer
+	jw := newJSONLWriter(&buf, nil)
+
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{"obs_connected": 1})
+	data.Instance = "backup"
+	data.Samples = data.Samples[:1]
+	if err := jw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	expected := `{"timestamp":"2025-12-23T10:00:00Z","instance":"backup","obs_connected":true,"events":[],"errors":[]}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+}
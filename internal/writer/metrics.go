@@ -1,23 +1,59 @@
 package writer
 
-import "time"
+import (
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
 
 // MetricsData holds all metrics data for a single measurement
 type MetricsData struct {
-	Timestamp           time.Time
-	ObsRTT              time.Duration
-	ObsPingError        error
-	GoogleRTT           time.Duration
-	GooglePingError     error
-	StreamActive        bool
-	OutputBytes         float64
-	OutputSkippedFrames float64
-	OutputFrames        float64
-	StreamError         error
-	ObsCpuUsage         float64
-	ObsMemoryUsage      float64
-	ObsStatsError       error
-	SystemCpuUsage      float64
-	SystemMemoryUsage   float64
-	SystemMetricsError  error
+	Timestamp time.Time
+	// Instance is the name of the OBS instance the row belongs to, empty when a single instance is monitored
+	Instance string
+	// Samples contains one sample per registered column, in column order
+	Samples []metric.Sample
+	// Events are the OBS events of the instance since the previous row
+	Events []Event
+	Errors []metric.CollectorError
+}
+
+// Sample returns the sample of the named column
+func (d MetricsData) Sample(name string) (metric.Sample, bool) {
+	for _, s := range d.Samples {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return metric.Sample{}, false
+}
+
+// sampleFor returns the sample of the column, an invalid sample when the row has none
+func (d MetricsData) sampleFor(column metric.Descriptor) metric.Sample {
+	if s, ok := d.Sample(column.Name); ok {
+		return s
+	}
+	return metric.Sample{Descriptor: column}
+}
+
+func (d MetricsData) errorsText() string {
+	text := ""
+	for _, e := range d.Errors {
+		if text != "" {
+			text += "; "
+		}
+		text += e.Error()
+	}
+	return text
+}
+
+func (d MetricsData) eventsText() string {
+	text := ""
+	for _, e := range d.Events {
+		if text != "" {
+			text += "; "
+		}
+		text += e.Message
+	}
+	return text
 }
@@ -1,178 +1,95 @@
 package writer
 
 import (
-	"fmt"
+	"errors"
 	"testing"
 	"time"
-)
 
-func TestMetricsData_Initialization(t *testing.T) {
-	data := MetricsData{
-		Timestamp:           time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC),
-		ObsRTT:              50 * time.Millisecond,
-		GoogleRTT:           25 * time.Millisecond,
-		StreamActive:        true,
-		OutputBytes:         1024.0,
-		OutputSkippedFrames: 5.0,
-		OutputFrames:        100.0,
-		ObsCpuUsage:         15.5,
-		ObsMemoryUsage:      512.0,
-		SystemCpuUsage:      45.2,
-		SystemMemoryUsage:   60.0,
-	}
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
 
-	if data.Timestamp.IsZero() {
-		t.Error("Expected Timestamp to be set")
-	}
-	if data.ObsRTT != 50*time.Millisecond {
-		t.Errorf("Expected ObsRTT to be 50ms, got %v", data.ObsRTT)
-	}
-	if data.StreamActive != true {
-		t.Error("Expected StreamActive to be true")
-	}
-	if data.OutputBytes != 1024.0 {
-		t.Errorf("Expected OutputBytes to be 1024.0, got %f", data.OutputBytes)
-	}
+var testColumns = []metric.Descriptor{
+	{Name: "obs_connected", Kind: metric.Bool},
+	{Name: "obs_rtt_ms", Unit: "ms", Kind: metric.Gauge, Precision: 2, Family: "rtt_ms", Labels: map[string]string{"target": "obs"}},
+	{Name: "google_rtt_ms", Unit: "ms", Kind: metric.Gauge, Precision: 2, Family: "rtt_ms", Labels: map[string]string{"target": "google"}},
+	{Name: "stream_active", Kind: metric.Bool},
+	{Name: "output_bytes", Unit: "bytes", Kind: metric.Counter},
+	{Name: "output_skipped_frames", Unit: "frames", Kind: metric.Counter},
+	{Name: "output_frames", Unit: "frames", Kind: metric.Counter},
+	{Name: "obs_cpu_percent", Unit: "percent", Kind: metric.Gauge, Precision: 2},
+	{Name: "obs_memory_mb", Unit: "MB", Kind: metric.Gauge, Precision: 2},
+	{Name: "system_cpu_percent", Unit: "percent", Kind: metric.Gauge, Precision: 2},
+	{Name: "system_memory_percent", Unit: "percent", Kind: metric.Gauge, Precision: 2},
 }
 
-func TestMetricsData_DefaultValues(t *testing.T) {
-	data := MetricsData{}
-
-	if !data.Timestamp.IsZero() {
-		t.Error("Expected default Timestamp to be zero")
-	}
-	if data.ObsRTT != 0 {
-		t.Errorf("Expected default ObsRTT to be 0, got %v", data.ObsRTT)
-	}
-	if data.GoogleRTT != 0 {
-		t.Errorf("Expected default GoogleRTT to be 0, got %v", data.GoogleRTT)
-	}
-	if data.StreamActive != false {
-		t.Error("Expected default StreamActive to be false")
-	}
-	if data.OutputBytes != 0 {
-		t.Errorf("Expected default OutputBytes to be 0, got %f", data.OutputBytes)
-	}
-	if data.OutputSkippedFrames != 0 {
-		t.Errorf("Expected default OutputSkippedFrames to be 0, got %f", data.OutputSkippedFrames)
-	}
-	if data.OutputFrames != 0 {
-		t.Errorf("Expected default OutputFrames to be 0, got %f", data.OutputFrames)
-	}
-	if data.ObsCpuUsage != 0 {
-		t.Errorf("Expected default ObsCpuUsage to be 0, got %f", data.ObsCpuUsage)
-	}
-	if data.ObsMemoryUsage != 0 {
-		t.Errorf("Expected default ObsMemoryUsage to be 0, got %f", data.ObsMemoryUsage)
-	}
-	if data.SystemCpuUsage != 0 {
-		t.Errorf("Expected default SystemCpuUsage to be 0, got %f", data.SystemCpuUsage)
-	}
-	if data.SystemMemoryUsage != 0 {
-		t.Errorf("Expected default SystemMemoryUsage to be 0, got %f", data.SystemMemoryUsage)
-	}
+// testRow builds a row with a sample for every test column, only the columns in values are valid
+func testRow(timestamp time.Time, values map[string]float64, errs ...metric.CollectorError) MetricsData {
+	data := MetricsData{Timestamp: timestamp, Errors: errs}
+	for _, column := range testColumns {
+		sample := metric.Sample{Descriptor: column}
+		if value, ok := values[column.Name]; ok {
+			sample = column.Sample(value)
+		}
+		data.Samples = append(data.Samples, sample)
+	}
+	return data
 }
 
-func TestMetricsData_ZeroValuesForOptionalFields(t *testing.T) {
-	data := MetricsData{
-		Timestamp:    time.Now(),
-		StreamActive: true,
-	}
+func TestMetricsData_Sample(t *testing.T) {
+	data := testRow(time.Now(), map[string]float64{"obs_rtt_ms": 50})
 
-	if data.ObsPingError != nil {
-		t.Error("Expected ObsPingError to be nil")
+	sample, ok := data.Sample("obs_rtt_ms")
+	if !ok {
+		t.Fatal("Expected obs_rtt_ms sample")
 	}
-	if data.GooglePingError != nil {
-		t.Error("Expected GooglePingError to be nil")
+	if !sample.Valid || sample.Value != 50 {
+		t.Errorf("Expected valid value 50, got %+v", sample)
 	}
-	if data.StreamError != nil {
-		t.Error("Expected StreamError to be nil")
+
+	sample, ok = data.Sample("google_rtt_ms")
+	if !ok {
+		t.Fatal("Expected google_rtt_ms sample")
 	}
-	if data.ObsStatsError != nil {
-		t.Error("Expected ObsStatsError to be nil")
+	if sample.Valid {
+		t.Error("Expected sample without a value to be invalid")
 	}
-	if data.SystemMetricsError != nil {
-		t.Error("Expected SystemMetricsError to be nil")
+
+	if _, ok := data.Sample("unknown"); ok {
+		t.Error("Expected no sample for an unknown column")
 	}
 }
 
-func TestMetricsData_WithErrors(t *testing.T) {
-	obsErr := fmt.Errorf("obs ping failed")
-	googleErr := fmt.Errorf("google ping failed")
-	streamErr := fmt.Errorf("stream error")
-	obsStatsErr := fmt.Errorf("obs stats error")
-	systemErr := fmt.Errorf("system metrics error")
+func TestMetricsData_SampleForMissingColumn(t *testing.T) {
+	data := MetricsData{Timestamp: time.Now()}
+	column := metric.Descriptor{Name: "obs_rtt_ms", Precision: 2}
 
-	data := MetricsData{
-		Timestamp:          time.Now(),
-		ObsPingError:       obsErr,
-		GooglePingError:    googleErr,
-		StreamError:        streamErr,
-		ObsStatsError:      obsStatsErr,
-		SystemMetricsError: systemErr,
-	}
+	sample := data.sampleFor(column)
 
-	if data.ObsPingError == nil {
-		t.Error("Expected ObsPingError to be set")
-	}
-	if data.GooglePingError == nil {
-		t.Error("Expected GooglePingError to be set")
-	}
-	if data.StreamError == nil {
-		t.Error("Expected StreamError to be set")
+	if sample.Valid {
+		t.Error("Expected missing column to be invalid")
 	}
-	if data.ObsStatsError == nil {
-		t.Error("Expected ObsStatsError to be set")
-	}
-	if data.SystemMetricsError == nil {
-		t.Error("Expected SystemMetricsError to be set")
+	if sample.Name != "obs_rtt_ms" {
+		t.Errorf("Expected descriptor of the column, got %q", sample.Name)
 	}
 }
 
-func TestMetricsData_HighValues(t *testing.T) {
-	data := MetricsData{
-		Timestamp:           time.Now(),
-		ObsRTT:              5000 * time.Millisecond,
-		GoogleRTT:           3000 * time.Millisecond,
-		StreamActive:        true,
-		OutputBytes:         999999999.0,
-		OutputSkippedFrames: 10000.0,
-		OutputFrames:        100000.0,
-		ObsCpuUsage:         99.9,
-		ObsMemoryUsage:      8192.0,
-		SystemCpuUsage:      100.0,
-		SystemMemoryUsage:   99.9,
-	}
-
-	if data.ObsRTT != 5000*time.Millisecond {
-		t.Errorf("Expected high ObsRTT value, got %v", data.ObsRTT)
-	}
-	if data.OutputBytes != 999999999.0 {
-		t.Errorf("Expected high OutputBytes value, got %f", data.OutputBytes)
-	}
-	if data.SystemCpuUsage != 100.0 {
-		t.Errorf("Expected SystemCpuUsage 100.0, got %f", data.SystemCpuUsage)
-	}
-}
+func TestMetricsData_ErrorsText(t *testing.T) {
+	data := testRow(time.Now(), nil,
+		metric.CollectorError{Collector: "obs_ping", Err: errors.New("obs ping failed")},
+		metric.CollectorError{Collector: "stream", Err: errors.New("stream error")},
+	)
 
-func TestMetricsData_NegativeValues(t *testing.T) {
-	data := MetricsData{
-		Timestamp:           time.Now(),
-		OutputBytes:         -100.0,
-		OutputSkippedFrames: -5.0,
-		ObsCpuUsage:         -10.0,
+	if got := data.errorsText(); got != "obs_ping: obs ping failed; stream: stream error" {
+		t.Errorf("Unexpected errors text %q", got)
 	}
-
-	if data.OutputBytes >= 0 {
-		t.Logf("Note: Negative OutputBytes accepted: %f", data.OutputBytes)
+	if got := testRow(time.Now(), nil).errorsText(); got != "" {
+		t.Errorf("Expected empty errors text, got %q", got)
 	}
 }
 
 func TestMetricsData_TimestampPrecision(t *testing.T) {
 	now := time.Date(2025, 12, 23, 10, 30, 45, 123456789, time.UTC)
-	data := MetricsData{
-		Timestamp: now,
-	}
+	data := testRow(now, nil)
 
 	if data.Timestamp != now {
 		t.Error("Timestamp precision not preserved")
@@ -181,18 +98,3 @@ func TestMetricsData_TimestampPrecision(t *testing.T) {
 		t.Errorf("Expected nanosecond precision, got %d", data.Timestamp.Nanosecond())
 	}
 }
-
-func TestMetricsData_PartialData(t *testing.T) {
-	data := MetricsData{
-		Timestamp:    time.Now(),
-		ObsRTT:       50 * time.Millisecond,
-		StreamActive: true,
-	}
-
-	if data.GoogleRTT != 0 {
-		t.Error("Expected unset GoogleRTT to be zero")
-	}
-	if data.OutputBytes != 0 {
-		t.Error("Expected unset OutputBytes to be zero")
-	}
-}
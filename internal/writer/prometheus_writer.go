@@ -0,0 +1,196 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const prometheusNamespace = "obs_monitor"
+
+// PrometheusWriter exposes the latest metrics row of every OBS instance on an HTTP /metrics endpoint.
+// Every column becomes a metric named after the column or its family, counters get the _total suffix.
+type PrometheusWriter struct {
+	listener net.Listener
+	server   *http.Server
+	// serveErr is the error of a failed HTTP server, it is reported by the next WriteMetrics
+	serveErr error
+	mu       sync.Mutex
+	// latest holds the latest row per OBS instance
+	latest map[string]MetricsData
+	// Rows contain deltas per writer interval, Prometheus counters need running totals.
+	// Columns and errors that are shared by all instances are part of every row of an interval,
+	// the counted-at times make sure they are only counted once per interval.
+	totals          map[string]float64
+	totalsCountedAt map[string]time.Time
+	errorsTotal     map[string]float64
+	errorsCountedAt map[string]time.Time
+
+	collectionErrors    *prometheus.Desc
+	lastUpdateTimestamp *prometheus.Desc
+}
+
+// NewPrometheusWriter starts an HTTP server on addr that serves the metrics in the Prometheus format.
+// The error counter of every named collector starts at 0.
+func NewPrometheusWriter(addr string, collectors []string) (*PrometheusWriter, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	pw := &PrometheusWriter{
+		listener:        listener,
+		latest:          map[string]MetricsData{},
+		totals:          map[string]float64{},
+		totalsCountedAt: map[string]time.Time{},
+		errorsTotal:     map[string]float64{},
+		errorsCountedAt: map[string]time.Time{},
+
+		collectionErrors:    newDesc("collection_errors_total", "Writer intervals in which a metric source reported an error.", []string{"source"}, nil),
+		lastUpdateTimestamp: newDesc("last_update_timestamp_seconds", "Time of the latest metrics row.", nil, nil),
+	}
+	for _, name := range collectors {
+		pw.errorsTotal[name] = 0
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(pw)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	pw.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := pw.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			pw.mu.Lock()
+			pw.serveErr = fmt.Errorf("prometheus server failed: %w", err)
+			pw.mu.Unlock()
+		}
+	}()
+
+	return pw, nil
+}
+
+func newDesc(name, help string, labels []string, constLabels prometheus.Labels) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(prometheusNamespace, "", name), help, labels, constLabels)
+}
+
+func columnDesc(column metric.Descriptor) *prometheus.Desc {
+	name := column.Name
+	if column.Family != "" {
+		name = column.Family
+	}
+	help := column.Help
+	if help == "" {
+		help = fmt.Sprintf("Value of %s.", name)
+	}
+	if column.Kind == metric.Counter {
+		name += "_total"
+	}
+	return newDesc(name, help, nil, column.Labels)
+}
+
+// Addr returns the address the HTTP server listens on
+func (pw *PrometheusWriter) Addr() string {
+	return pw.listener.Addr().String()
+}
+
+// WriteMetrics stores the row so it is served on the next scrape, it reports a failed HTTP server once
+func (pw *PrometheusWriter) WriteMetrics(data MetricsData) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.latest[data.Instance] = data
+
+	for _, s := range data.Samples {
+		if s.Kind != metric.Counter {
+			continue
+		}
+		key := seriesKey(s.Descriptor)
+		if pw.totalsCountedAt[key].Equal(data.Timestamp) {
+			continue
+		}
+		pw.totalsCountedAt[key] = data.Timestamp
+		if s.Valid {
+			pw.totals[key] += s.Value
+		} else if _, ok := pw.totals[key]; !ok {
+			pw.totals[key] = 0
+		}
+	}
+	for _, e := range data.Errors {
+		if pw.errorsCountedAt[e.Collector].Equal(data.Timestamp) {
+			continue
+		}
+		pw.errorsCountedAt[e.Collector] = data.Timestamp
+		pw.errorsTotal[e.Collector]++
+	}
+
+	err := pw.serveErr
+	pw.serveErr = nil
+	return err
+}
+
+// Close stops the HTTP server
+func (pw *PrometheusWriter) Close() error {
+	return pw.server.Close()
+}
+
+// Describe implements prometheus.Collector.
+// No descriptors are sent, the columns are only known once rows are written.
+func (pw *PrometheusWriter) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect implements prometheus.Collector
+func (pw *PrometheusWriter) Collect(ch chan<- prometheus.Metric) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if len(pw.latest) == 0 {
+		return
+	}
+
+	// Shared columns are part of the row of every instance, but may only be exposed once
+	exposed := map[string]bool{}
+	var lastUpdate time.Time
+	for _, instance := range slices.Sorted(maps.Keys(pw.latest)) {
+		data := pw.latest[instance]
+		if data.Timestamp.After(lastUpdate) {
+			lastUpdate = data.Timestamp
+		}
+
+		for _, s := range data.Samples {
+			key := seriesKey(s.Descriptor)
+			if exposed[key] {
+				continue
+			}
+			switch {
+			case s.Kind == metric.Counter:
+				ch <- prometheus.MustNewConstMetric(columnDesc(s.Descriptor), prometheus.CounterValue, pw.totals[key])
+			case s.Valid:
+				ch <- prometheus.MustNewConstMetric(columnDesc(s.Descriptor), prometheus.GaugeValue, s.Value)
+			}
+			exposed[key] = true
+		}
+	}
+	for source, count := range pw.errorsTotal {
+		ch <- prometheus.MustNewConstMetric(pw.collectionErrors, prometheus.CounterValue, count, source)
+	}
+	ch <- prometheus.MustNewConstMetric(pw.lastUpdateTimestamp, prometheus.GaugeValue, float64(lastUpdate.UnixMilli())/1000.0)
+}
+
+// seriesKey identifies the time series of a column, columns of different instances differ in their labels
+func seriesKey(column metric.Descriptor) string {
+	return columnDesc(column).String()
+}
@@ -0,0 +1,229 @@
+package writer
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
+
+func newTestPrometheusWriter(t *testing.T) *PrometheusWriter {
+	t.Helper()
+
+	pw, err := NewPrometheusWriter("127.0.0.1:0", []string{"obs_ping", "google_ping", "stream", "obs_stats", "system"})
+	if err != nil {
+		t.Fatalf("NewPrometheusWriter failed: %v", err)
+	}
+	t.Cleanup(func() { pw.Close() })
+	return pw
+}
+
+func scrape(t *testing.T, pw *PrometheusWriter) string {
+	t.Helper()
+
+	resp, err := http.Get("http://" + pw.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestPrometheusWriter_NoDataBeforeFirstRow(t *testing.T) {
+	pw := newTestPrometheusWriter(t)
+
+	body := scrape(t, pw)
+
+	if strings.Contains(body, "obs_monitor_") {
+		t.Errorf("Expected no metrics before the first row, got:\n%s", body)
+	}
+}
+
+func TestPrometheusWriter_ExposesGauges(t *testing.T) {
+	pw := newTestPrometheusWriter(t)
+
+	data := testRow(time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC), map[string]float64{
+		"obs_connected":         1,
+		"obs_rtt_ms":            50,
+		"google_rtt_ms":         25,
+		"stream_active":         1,
+		"obs_cpu_percent":       15.5,
+		"obs_memory_mb":         512.0,
+		"system_cpu_percent":    45.2,
+		"system_memory_percent": 60.0,
+	})
+	if err := pw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	body := scrape(t, pw)
+
+	expected := []string{
+		"obs_monitor_obs_connected 1",
+		`obs_monitor_rtt_ms{target="obs"} 50`,
+		`obs_monitor_rtt_ms{target="google"} 25`,
+		"obs_monitor_stream_active 1",
+		"obs_monitor_obs_cpu_percent 15.5",
+		"obs_monitor_obs_memory_mb 512",
+		"obs_monitor_system_cpu_percent 45.2",
+		"obs_monitor_system_memory_percent 60",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestPrometheusWriter_CountersAreTotals(t *testing.T) {
+	pw := newTestPrometheusWriter(t)
+
+	for i := 0; i < 3; i++ {
+		data := testRow(time.Date(2025, 12, 23, 10, 0, i, 0, time.UTC), map[string]float64{
+			"output_bytes":          1000,
+			"output_skipped_frames": 2,
+			"output_frames":         30,
+		})
+		if err := pw.WriteMetrics(data); err != nil {
+			t.Fatalf("WriteMetrics failed: %v", err)
+		}
+	}
+
+	body := scrape(t, pw)
+
+	expected := []string{
+		"obs_monitor_output_bytes_total 3000",
+		"obs_monitor_output_skipped_frames_total 6",
+		"obs_monitor_output_frames_total 90",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestPrometheusWriter_ErrorCounters(t *testing.T) {
+	pw := newTestPrometheusWriter(t)
+
+	rows := []MetricsData{
+		testRow(time.Now(), nil, metric.CollectorError{Collector: "obs_ping", Err: errors.New("timeout")}),
+		testRow(time.Now(), nil,
+			metric.CollectorError{Collector: "obs_ping", Err: errors.New("timeout")},
+			metric.CollectorError{Collector: "stream", Err: errors.New("failed")},
+		),
+		testRow(time.Now(), nil),
+	}
+	for _, data := range rows {
+		if err := pw.WriteMetrics(data); err != nil {
+			t.Fatalf("WriteMetrics failed: %v", err)
+		}
+	}
+
+	body := scrape(t, pw)
+
+	expected := []string{
+		`obs_monitor_collection_errors_total{source="obs_ping"} 2`,
+		`obs_monitor_collection_errors_total{source="stream"} 1`,
+		`obs_monitor_collection_errors_total{source="system"} 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, `rtt_ms{target="obs"}`) {
+		t.Error("RTT should not be exposed when the ping failed")
+	}
+}
+
+func TestPrometheusWriter_InvalidAddress(t *testing.T) {
+	_, err := NewPrometheusWriter("not-an-address", nil)
+	if err == nil {
+		t.Error("Expected error for invalid listen address")
+	}
+}
+
+func TestPrometheusWriter_Instances(t *testing.T) {
+	pw := newTestPrometheusWriter(t)
+
+	labeled := func(d metric.Descriptor, instance string) metric.Descriptor {
+		d.Labels = map[string]string{"obs_instance": instance}
+		return d
+	}
+	bytesColumn := metric.Descriptor{Name: "output_bytes", Kind: metric.Counter}
+	systemColumn := metric.Descriptor{Name: "system_cpu_percent", Kind: metric.Gauge}
+	sharedErr := metric.CollectorError{Collector: "system", Err: errors.New("failed")}
+
+	for i := 0; i < 2; i++ {
+		timestamp := time.Date(2025, 12, 23, 10, 0, i, 0, time.UTC)
+		for _, instance := range []string{"main", "backup"} {
+			data := MetricsData{
+				Timestamp: timestamp,
+				Instance:  instance,
+				Samples: []metric.Sample{
+					labeled(bytesColumn, instance).Sample(1000),
+					systemColumn.Sample(25),
+				},
+				Errors: []metric.CollectorError{sharedErr},
+			}
+			if err := pw.WriteMetrics(data); err != nil {
+				t.Fatalf("WriteMetrics failed: %v", err)
+			}
+		}
+	}
+
+	body := scrape(t, pw)
+
+	expected := []string{
+		`obs_monitor_output_bytes_total{obs_instance="backup"} 2000`,
+		`obs_monitor_output_bytes_total{obs_instance="main"} 2000`,
+		`obs_monitor_system_cpu_percent 25`,
+		`obs_monitor_collection_errors_total{source="system"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+	if strings.Count(body, "\nobs_monitor_system_cpu_percent ") != 1 {
+		t.Errorf("Expected the shared column to be exposed once, got:\n%s", body)
+	}
+}
+
+func TestPrometheusWriter_ReportsServerFailure(t *testing.T) {
+	pw := newTestPrometheusWriter(t)
+	pw.listener.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := pw.WriteMetrics(MetricsData{Timestamp: time.Now()})
+		if err != nil {
+			if !strings.Contains(err.Error(), "prometheus server failed") {
+				t.Errorf("Unexpected error %v", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the failed server to be reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := pw.WriteMetrics(MetricsData{Timestamp: time.Now()}); err != nil {
+		t.Errorf("Expected the failure to be reported once, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const sessionTimeFormat = "2006-01-02-15-04-05"
+
+// StreamObserver is implemented by writers that follow the stream of the OBS instances
+type StreamObserver interface {
+	StreamStarted(instance string, at time.Time) error
+	StreamStopped(instance string, at time.Time) error
+}
+
+// SessionWriter writes a CSV file per stream session, rows written while no stream is live are dropped.
+// The files are named after the start of the stream, e.g. obs-monitor-2025-12-23-15-01-25.csv,
+// and with multiple instances after the instance too, e.g. obs-monitor-backup-2025-12-23-15-01-25.csv.
+type SessionWriter struct {
+	dir  string
+	info SessionInfo
+	// sessions holds the open session file per OBS instance
+	sessions map[string]*session
+	mu       sync.Mutex
+}
+
+type session struct {
+	writer   *CSVWriter
+	metadata []string
+}
+
+// NewSessionWriter creates a session writer that writes its files to dir, the directory is created when missing
+func NewSessionWriter(dir string, info SessionInfo) (*SessionWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	return &SessionWriter{
+		dir:      dir,
+		info:     info,
+		sessions: map[string]*session{},
+	}, nil
+}
+
+// StreamStarted opens the session file of the instance, a session that is already open is kept
+func (sw *SessionWriter) StreamStarted(instance string, at time.Time) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if _, ok := sw.sessions[instance]; ok {
+		return nil
+	}
+
+	name := "obs-monitor-"
+	if instance != "" {
+		name += instance + "-"
+	}
+	filename := filepath.Join(sw.dir, name+at.Format(sessionTimeFormat)+".csv")
+
+	metadata := append(csvMetadata(sw.info.ObsVersion, sw.info.StreamDomain),
+		fmt.Sprintf("Stream started: %s", at.Format(time.RFC3339)))
+	if instance != "" {
+		metadata = slices.Insert(metadata, 0, fmt.Sprintf("Instance: %s", instance))
+	}
+
+	// A session file holds a single instance, so it has no instance column
+	writer, err := newCSVWriter(filename, metadata, sw.info.Columns, nil)
+	if err != nil {
+		return err
+	}
+	sw.sessions[instance] = &session{writer: writer, metadata: metadata}
+	return nil
+}
+
+// StreamStopped records the end of the stream in the header of the session file and closes it
+func (sw *SessionWriter) StreamStopped(instance string, at time.Time) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	s, ok := sw.sessions[instance]
+	if !ok {
+		return nil
+	}
+	delete(sw.sessions, instance)
+
+	return s.writer.closeWithMetadata(append(s.metadata, fmt.Sprintf("Stream stopped: %s", at.Format(time.RFC3339))))
+}
+
+// WriteMetrics writes the row to the session file of its instance, when its stream is live
+func (sw *SessionWriter) WriteMetrics(data MetricsData) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	s, ok := sw.sessions[data.Instance]
+	if !ok {
+		return nil
+	}
+	return s.writer.WriteMetrics(data)
+}
+
+// Close closes the open session files, their header records that the monitor stopped during the stream
+func (sw *SessionWriter) Close() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	var errs []error
+	now := time.Now()
+	for instance, s := range sw.sessions {
+		metadata := append(s.metadata, fmt.Sprintf("Monitor stopped: %s", now.Format(time.RFC3339)))
+		if err := s.writer.closeWithMetadata(metadata); err != nil {
+			errs = append(errs, err)
+		}
+		delete(sw.sessions, instance)
+	}
+	return errors.Join(errs...)
+}
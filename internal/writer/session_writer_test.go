@@ -0,0 +1,106 @@
+package writer
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readCSV(t *testing.T, filename string) [][]string {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Failed to open CSV file: %v", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	return records
+}
+
+func TestSessionWriter_FilePerStream(t *testing.T) {
+	dir := t.TempDir()
+	sw, err := NewSessionWriter(dir, SessionInfo{ObsVersion: "30.0.0", StreamDomain: "live.twitch.tv", Columns: testColumns})
+	if err != nil {
+		t.Fatalf("NewSessionWriter failed: %v", err)
+	}
+
+	start := time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC)
+	idle := testRow(start.Add(-time.Second), map[string]float64{"stream_active": 0})
+	live := testRow(start.Add(time.Second), map[string]float64{"stream_active": 1})
+
+	if err := sw.WriteMetrics(idle); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	if err := sw.StreamStarted("", start); err != nil {
+		t.Fatalf("StreamStarted failed: %v", err)
+	}
+	if err := sw.WriteMetrics(live); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	if err := sw.StreamStopped("", start.Add(2*time.Second)); err != nil {
+		t.Fatalf("StreamStopped failed: %v", err)
+	}
+	if err := sw.WriteMetrics(idle); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	records := readCSV(t, filepath.Join(dir, "obs-monitor-2025-12-23-10-00-00.csv"))
+	metadata := strings.Join(records[0], ",")
+	if !strings.Contains(metadata, "Stream started: 2025-12-23T10:00:00Z") || !strings.Contains(metadata, "Stream stopped: 2025-12-23T10:00:02Z") {
+		t.Errorf("Expected the stream start and stop in the metadata, got %v", records[0])
+	}
+	if records[1][0] != "timestamp" {
+		t.Errorf("Expected the column header after the metadata, got %v", records[1])
+	}
+	if len(records) != 3 || records[2][0] != "2025-12-23T10:00:01Z" {
+		t.Errorf("Expected only the live row, got %v", records[2:])
+	}
+}
+
+func TestSessionWriter_CloseDuringStream(t *testing.T) {
+	dir := t.TempDir()
+	sw, err := NewSessionWriter(dir, SessionInfo{Columns: testColumns, Instances: []string{"main", "backup"}})
+	if err != nil {
+		t.Fatalf("NewSessionWriter failed: %v", err)
+	}
+
+	start := time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC)
+	if err := sw.StreamStarted("backup", start); err != nil {
+		t.Fatalf("StreamStarted failed: %v", err)
+	}
+	row := testRow(start, map[string]float64{"stream_active": 1})
+	row.Instance = "main"
+	sw.WriteMetrics(row)
+	row.Instance = "backup"
+	sw.WriteMetrics(row)
+
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if len(files) != 1 || filepath.Base(files[0]) != "obs-monitor-backup-2025-12-23-10-00-00.csv" {
+		t.Fatalf("Expected a session file of the backup instance only, got %v", files)
+	}
+
+	records := readCSV(t, files[0])
+	metadata := strings.Join(records[0], ",")
+	if !strings.Contains(metadata, "Instance: backup") || !strings.Contains(metadata, "Monitor stopped:") {
+		t.Errorf("Expected the instance and the monitor stop in the metadata, got %v", records[0])
+	}
+	if len(records) != 3 {
+		t.Errorf("Expected a single row of the backup instance, got %d records", len(records))
+	}
+}
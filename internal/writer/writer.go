@@ -0,0 +1,209 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
+)
+
+// Writer is a sink for metrics rows
+type Writer interface {
+	WriteMetrics(data MetricsData) error
+	Close() error
+}
+
+// SessionInfo describes the monitoring session a writer is created for
+type SessionInfo struct {
+	ObsVersion   string
+	StreamDomain string
+	// Columns are the columns of every row, in order
+	Columns []metric.Descriptor
+	// Collectors are the names of the collectors that produce the columns
+	Collectors []string
+	// Instances are the names of the monitored OBS instances, rows get an instance column when there are any
+	Instances []string
+}
+
+// Factory creates a writer for the target part of an output spec
+type Factory func(target string, info SessionInfo) (Writer, error)
+
+var (
+	factories = map[string]Factory{
+		"console": func(target string, info SessionInfo) (Writer, error) {
+			return NewConsoleWriter(info.Columns, info.Instances), nil
+		},
+		"csv": func(target string, info SessionInfo) (Writer, error) {
+			if target == "" {
+				return nil, fmt.Errorf("csv output requires a file name")
+			}
+			return NewCSVWriter(target, info.ObsVersion, info.StreamDomain, info.Columns, info.Instances)
+		},
+		"jsonl": func(target string, info SessionInfo) (Writer, error) {
+			if target == "" {
+				return nil, fmt.Errorf("jsonl output requires a file name or - for stdout")
+			}
+			return NewJSONLWriter(target)
+		},
+		"events": func(target string, info SessionInfo) (Writer, error) {
+			if target == "" {
+				target = "events.jsonl"
+			}
+			return NewEventLogWriter(target)
+		},
+		"session": func(target string, info SessionInfo) (Writer, error) {
+			if target == "" {
+				target = "."
+			}
+			return NewSessionWriter(target, info)
+		},
+		"prometheus": func(target string, info SessionInfo) (Writer, error) {
+			if target == "" {
+				return nil, fmt.Errorf("prometheus output requires a listen address")
+			}
+			return NewPrometheusWriter(target, info.Collectors)
+		},
+	}
+	factoriesMu sync.RWMutex
+)
+
+// Register makes a writer kind available to New, so it can be selected as an output
+func Register(kind string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[kind] = factory
+}
+
+// Kinds returns the names of all registered writer kinds
+func Kinds() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	kinds := make([]string, 0, len(factories))
+	for kind := range factories {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// ValidateSpec checks that the kind of an output spec is registered, without creating the writer
+func ValidateSpec(spec string) error {
+	kind, _, _ := strings.Cut(spec, ":")
+
+	factoriesMu.RLock()
+	_, ok := factories[kind]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("unknown output kind %q, expected one of %s", kind, strings.Join(Kinds(), ", "))
+	}
+	return nil
+}
+
+// New creates a writer from an output spec in the form kind[:target], e.g. csv:metrics.csv
+func New(spec string, info SessionInfo) (Writer, error) {
+	if err := ValidateSpec(spec); err != nil {
+		return nil, err
+	}
+
+	kind, target, _ := strings.Cut(spec, ":")
+
+	factoriesMu.RLock()
+	factory := factories[kind]
+	factoriesMu.RUnlock()
+
+	return factory(target, info)
+}
+
+// MultiWriter fans each metrics row out to all of its writers.
+// A failing writer does not prevent the others from receiving the row.
+type MultiWriter struct {
+	names   []string
+	writers []Writer
+}
+
+func NewMultiWriter() *MultiWriter {
+	return &MultiWriter{}
+}
+
+// Add appends a writer, the name is used to identify it in errors
+func (mw *MultiWriter) Add(name string, w Writer) {
+	mw.names = append(mw.names, name)
+	mw.writers = append(mw.writers, w)
+}
+
+func (mw *MultiWriter) Len() int {
+	return len(mw.writers)
+}
+
+func (mw *MultiWriter) WriteMetrics(data MetricsData) error {
+	return mw.WriteMetricsExcept(data, nil)
+}
+
+// WriteMetricsExcept writes the row to the writers for whose name skip returns false, a nil skip writes to all writers
+func (mw *MultiWriter) WriteMetricsExcept(data MetricsData, skip func(name string) bool) error {
+	var errs []error
+	for i, w := range mw.writers {
+		if skip != nil && skip(mw.names[i]) {
+			continue
+		}
+		if err := w.WriteMetrics(data); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", mw.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteEvent passes an OBS event to the writers that record events
+func (mw *MultiWriter) WriteEvent(event Event) error {
+	var errs []error
+	for i, w := range mw.writers {
+		if ew, ok := w.(EventWriter); ok {
+			if err := ew.WriteEvent(event); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", mw.names[i], err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StreamStarted notifies the writers that follow the stream that the stream of an instance started
+func (mw *MultiWriter) StreamStarted(instance string, at time.Time) error {
+	var errs []error
+	for i, w := range mw.writers {
+		if o, ok := w.(StreamObserver); ok {
+			if err := o.StreamStarted(instance, at); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", mw.names[i], err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StreamStopped notifies the writers that follow the stream that the stream of an instance stopped
+func (mw *MultiWriter) StreamStopped(instance string, at time.Time) error {
+	var errs []error
+	for i, w := range mw.writers {
+		if o, ok := w.(StreamObserver); ok {
+			if err := o.StreamStopped(instance, at); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", mw.names[i], err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (mw *MultiWriter) Close() error {
+	var errs []error
+	for i, w := range mw.writers {
+		if err := w.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", mw.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
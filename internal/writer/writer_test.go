@@ -0,0 +1,265 @@
+package writer
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingWriter struct {
+	rows   []MetricsData
+	err    error
+	closed bool
+}
+
+func (w *recordingWriter) WriteMetrics(data MetricsData) error {
+	w.rows = append(w.rows, data)
+	return w.err
+}
+
+func (w *recordingWriter) Close() error {
+	w.closed = true
+	return w.err
+}
+
+func TestNew_Specs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{
+			name: "console",
+			spec: "console",
+		},
+		{
+			name: "csv with file",
+			spec: "csv:" + filepath.Join(tmpDir, "test.csv"),
+		},
+		{
+			name:    "csv without file",
+			spec:    "csv",
+			wantErr: true,
+		},
+		{
+			name: "jsonl with file",
+			spec: "jsonl:" + filepath.Join(tmpDir, "test.jsonl"),
+		},
+		{
+			name:    "jsonl without target",
+			spec:    "jsonl",
+			wantErr: true,
+		},
+		{
+			name: "session with directory",
+			spec: "session:" + filepath.Join(tmpDir, "sessions"),
+		},
+		{
+			name: "events with file",
+			spec: "events:" + filepath.Join(tmpDir, "events.jsonl"),
+		},
+		{
+			name:    "unknown kind",
+			spec:    "carrier-pigeon:home",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(tt.spec, SessionInfo{ObsVersion: "30.0.0", StreamDomain: "live.twitch.tv"})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for spec %q", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			t.Cleanup(func() { w.Close() })
+		})
+	}
+}
+
+func TestRegister_MakesKindAvailable(t *testing.T) {
+	recorder := &recordingWriter{}
+	var gotTarget string
+	Register("test-recorder", func(target string, info SessionInfo) (Writer, error) {
+		gotTarget = target
+		return recorder, nil
+	})
+	t.Cleanup(func() {
+		factoriesMu.Lock()
+		delete(factories, "test-recorder")
+		factoriesMu.Unlock()
+	})
+
+	if !slices.Contains(Kinds(), "test-recorder") {
+		t.Errorf("Expected registered kind in %v", Kinds())
+	}
+
+	w, err := New("test-recorder:somewhere", SessionInfo{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if w != recorder {
+		t.Error("Expected writer from registered factory")
+	}
+	if gotTarget != "somewhere" {
+		t.Errorf("Expected target 'somewhere', got %q", gotTarget)
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	if err := ValidateSpec("csv:metrics.csv"); err != nil {
+		t.Errorf("Expected csv spec to be valid, got %v", err)
+	}
+	if err := ValidateSpec("influx:localhost"); err == nil {
+		t.Error("Expected error for unknown kind")
+	}
+}
+
+func TestMultiWriter_WriteMetrics_FansOut(t *testing.T) {
+	first := &recordingWriter{}
+	second := &recordingWriter{}
+
+	mw := NewMultiWriter()
+	mw.Add("first", first)
+	mw.Add("second", second)
+
+	data := MetricsData{Timestamp: time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC)}
+	if err := mw.WriteMetrics(data); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+
+	if len(first.rows) != 1 || len(second.rows) != 1 {
+		t.Errorf("Expected both writers to receive one row, got %d and %d", len(first.rows), len(second.rows))
+	}
+}
+
+func TestMultiWriter_WriteMetrics_IsolatesErrors(t *testing.T) {
+	failing := &recordingWriter{err: errors.New("disk full")}
+	healthy := &recordingWriter{}
+
+	mw := NewMultiWriter()
+	mw.Add("failing", failing)
+	mw.Add("healthy", healthy)
+
+	err := mw.WriteMetrics(MetricsData{Timestamp: time.Now()})
+	if err == nil {
+		t.Fatal("Expected error from failing writer")
+	}
+	if !strings.Contains(err.Error(), "failing: disk full") {
+		t.Errorf("Expected error to name the failing writer, got %v", err)
+	}
+	if len(healthy.rows) != 1 {
+		t.Error("Healthy writer should still receive the row")
+	}
+}
+
+func TestMultiWriter_WriteMetricsExcept_SkipsWriters(t *testing.T) {
+	csv := &recordingWriter{}
+	prometheus := &recordingWriter{}
+
+	mw := NewMultiWriter()
+	mw.Add("csv:metrics.csv", csv)
+	mw.Add("prometheus::9090", prometheus)
+
+	skip := func(name string) bool { return strings.HasPrefix(name, "csv") }
+	if err := mw.WriteMetricsExcept(MetricsData{Timestamp: time.Now()}, skip); err != nil {
+		t.Fatalf("WriteMetricsExcept failed: %v", err)
+	}
+
+	if len(csv.rows) != 0 || len(prometheus.rows) != 1 {
+		t.Errorf("Expected only the prometheus writer to receive the row, got %d and %d", len(csv.rows), len(prometheus.rows))
+	}
+}
+
+func TestMultiWriter_Close_ClosesAll(t *testing.T) {
+	failing := &recordingWriter{err: errors.New("close failed")}
+	healthy := &recordingWriter{}
+
+	mw := NewMultiWriter()
+	mw.Add("failing", failing)
+	mw.Add("healthy", healthy)
+
+	if err := mw.Close(); err == nil {
+		t.Error("Expected close error to be reported")
+	}
+	if !failing.closed || !healthy.closed {
+		t.Error("Expected all writers to be closed")
+	}
+}
+
+type observingWriter struct {
+	recordingWriter
+	events []string
+}
+
+func (w *observingWriter) StreamStarted(instance string, at time.Time) error {
+	w.events = append(w.events, "started "+instance)
+	return nil
+}
+
+func (w *observingWriter) StreamStopped(instance string, at time.Time) error {
+	w.events = append(w.events, "stopped "+instance)
+	return nil
+}
+
+func TestMultiWriter_StreamEvents(t *testing.T) {
+	observer := &observingWriter{}
+
+	mw := NewMultiWriter()
+	mw.Add("plain", &recordingWriter{})
+	mw.Add("observer", observer)
+
+	at := time.Date(2025, 12, 23, 10, 0, 0, 0, time.UTC)
+	if err := mw.StreamStarted("main", at); err != nil {
+		t.Fatalf("StreamStarted failed: %v", err)
+	}
+	if err := mw.StreamStopped("main", at); err != nil {
+		t.Fatalf("StreamStopped failed: %v", err)
+	}
+
+	if !slices.Equal(observer.events, []string{"started main", "stopped main"}) {
+		t.Errorf("Expected the observer to follow the stream, got %v", observer.events)
+	}
+}
+
+type eventWriter struct {
+	recordingWriter
+	events []Event
+}
+
+func (w *eventWriter) WriteEvent(event Event) error {
+	w.events = append(w.events, event)
+	return w.err
+}
+
+func TestMultiWriter_WriteEvent(t *testing.T) {
+	plain := &recordingWriter{}
+	failing := &eventWriter{recordingWriter: recordingWriter{err: errors.New("disk full")}}
+	events := &eventWriter{}
+
+	mw := NewMultiWriter()
+	mw.Add("plain", plain)
+	mw.Add("failing", failing)
+	mw.Add("events", events)
+
+	err := mw.WriteEvent(Event{Type: "CurrentProgramSceneChanged", Message: "scene Live"})
+	if err == nil || !strings.Contains(err.Error(), "failing: disk full") {
+		t.Errorf("Expected the error of the failing writer, got %v", err)
+	}
+	if len(events.events) != 1 || events.events[0].Message != "scene Live" {
+		t.Errorf("Expected the event to reach the event writer, got %v", events.events)
+	}
+	if len(plain.rows) != 0 {
+		t.Errorf("Expected no rows for the plain writer, got %v", plain.rows)
+	}
+}
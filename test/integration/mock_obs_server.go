@@ -2,10 +2,12 @@ package integration
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -20,15 +22,56 @@ type MockOBSServer struct {
 	outputBytes      float64
 	skippedFrames    float64
 	totalFrames      float64
+	congestion       float64
+	reconnecting     bool
+	recordActive     bool
+	recordBytes      float64
+	outputs          map[string]MockOutput
+	outputOrder      []string
 	statsMu          sync.RWMutex
 	cpuUsage         float64
 	memoryUsage      float64
 	disconnectClient bool
 	disconnectMu     sync.RWMutex
+	streamServer     string
+	streamServerMu   sync.RWMutex
+	// writeMu serializes the writes of responses and events, websocket connections support a single writer
+	writeMu sync.Mutex
+}
+
+// MockOutput is an output of GetOutputList, like the outputs of multistream plugins
+type MockOutput struct {
+	Active        bool
+	Bytes         float64
+	Frames        float64
+	SkippedFrames float64
+	Congestion    float64
+	Reconnecting  bool
 }
 
 func NewMockOBSServer() *MockOBSServer {
-	mock := &MockOBSServer{
+	mock := newMockOBSServer()
+	mock.server = httptest.NewServer(http.HandlerFunc(mock.handleWebSocket))
+	return mock
+}
+
+// NewMockOBSServerOn starts the mock on a fixed address, e.g. to simulate OBS starting after the monitor
+func NewMockOBSServerOn(addr string) (*MockOBSServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mock := newMockOBSServer()
+	mock.server = httptest.NewUnstartedServer(http.HandlerFunc(mock.handleWebSocket))
+	mock.server.Listener.Close()
+	mock.server.Listener = listener
+	mock.server.Start()
+	return mock, nil
+}
+
+func newMockOBSServer() *MockOBSServer {
+	return &MockOBSServer{
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
@@ -36,10 +79,9 @@ func NewMockOBSServer() *MockOBSServer {
 		outputBytes:  0,
 		cpuUsage:     10.5,
 		memoryUsage:  256.0,
+		streamServer: "rtmp://test-ingest.example.com/app",
+		outputs:      map[string]MockOutput{},
 	}
-
-	mock.server = httptest.NewServer(http.HandlerFunc(mock.handleWebSocket))
-	return mock
 }
 
 func (m *MockOBSServer) URL() string {
@@ -62,6 +104,107 @@ func (m *MockOBSServer) SetStreamActive(active bool) {
 	m.streamActive = active
 }
 
+// StartStream makes the stream active and sends the StreamStateChanged event to all clients
+func (m *MockOBSServer) StartStream() {
+	m.SetStreamActive(true)
+	m.broadcastEvent("StreamStateChanged", map[string]interface{}{
+		"outputActive": true,
+		"outputState":  "OBS_WEBSOCKET_OUTPUT_STARTED",
+	})
+}
+
+// StopStream makes the stream inactive and sends the StreamStateChanged event to all clients
+func (m *MockOBSServer) StopStream() {
+	m.SetStreamActive(false)
+	m.broadcastEvent("StreamStateChanged", map[string]interface{}{
+		"outputActive": false,
+		"outputState":  "OBS_WEBSOCKET_OUTPUT_STOPPED",
+	})
+}
+
+// StartRecord makes the recording active and sends the RecordStateChanged event to all clients
+func (m *MockOBSServer) StartRecord() {
+	m.setRecordActive(true)
+	m.broadcastEvent("RecordStateChanged", map[string]interface{}{
+		"outputActive": true,
+		"outputState":  "OBS_WEBSOCKET_OUTPUT_STARTED",
+	})
+}
+
+// StopRecord makes the recording inactive and sends the RecordStateChanged event to all clients
+func (m *MockOBSServer) StopRecord() {
+	m.setRecordActive(false)
+	m.broadcastEvent("RecordStateChanged", map[string]interface{}{
+		"outputActive": false,
+		"outputPath":   "/recordings/2025-12-23 15-01-25.mkv",
+		"outputState":  "OBS_WEBSOCKET_OUTPUT_STOPPED",
+	})
+}
+
+// SetProgramScene sends the CurrentProgramSceneChanged event to all clients
+func (m *MockOBSServer) SetProgramScene(name string) {
+	m.broadcastEvent("CurrentProgramSceneChanged", map[string]interface{}{
+		"sceneName": name,
+		"sceneUuid": "b0d6f7a6-3f4c-4cb5-9b65-5c1a4f3e2d10",
+	})
+}
+
+// SendVolumeMeters sends the InputVolumeMeters event with a stereo level per input to all clients, as a multiplier
+func (m *MockOBSServer) SendVolumeMeters(levels map[string]float64) {
+	inputs := []interface{}{}
+	for name, level := range levels {
+		channel := []float64{level, level, level}
+		inputs = append(inputs, map[string]interface{}{
+			"inputName":      name,
+			"inputLevelsMul": [][]float64{channel, channel},
+		})
+	}
+	m.broadcastEvent("InputVolumeMeters", map[string]interface{}{"inputs": inputs})
+}
+
+func (m *MockOBSServer) setRecordActive(active bool) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	m.recordActive = active
+	m.recordBytes = 0
+}
+
+// IncrementRecordBytes adds bytes to the active recording
+func (m *MockOBSServer) IncrementRecordBytes(bytes float64) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	m.recordBytes += bytes
+}
+
+func (m *MockOBSServer) broadcastEvent(eventType string, data map[string]interface{}) {
+	event := map[string]interface{}{
+		"op": 5,
+		"d": map[string]interface{}{
+			"eventType":   eventType,
+			"eventIntent": 64,
+			"eventData":   data,
+		},
+	}
+
+	m.clientsMu.Lock()
+	defer m.clientsMu.Unlock()
+	for _, client := range m.clients {
+		m.writeJSON(client, event)
+	}
+}
+
+func (m *MockOBSServer) writeJSON(conn *websocket.Conn, v interface{}) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return conn.WriteJSON(v)
+}
+
+func (m *MockOBSServer) SetStreamServer(server string) {
+	m.streamServerMu.Lock()
+	defer m.streamServerMu.Unlock()
+	m.streamServer = server
+}
+
 func (m *MockOBSServer) SetStats(cpu, memory float64) {
 	m.statsMu.Lock()
 	defer m.statsMu.Unlock()
@@ -77,6 +220,26 @@ func (m *MockOBSServer) IncrementStreamMetrics(bytes, skipped, frames float64) {
 	m.totalFrames += frames
 }
 
+// SetStreamHealth sets the congestion and reconnecting state of the stream output
+func (m *MockOBSServer) SetStreamHealth(congestion float64, reconnecting bool) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	m.congestion = congestion
+	m.reconnecting = reconnecting
+}
+
+// SetOutput adds an output or replaces its status
+func (m *MockOBSServer) SetOutput(name string, output MockOutput) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	if _, ok := m.outputs[name]; !ok {
+		m.outputOrder = append(m.outputOrder, name)
+	}
+	m.outputs[name] = output
+}
+
+// DisconnectClients closes all connections the way OBS does when it shuts down
+// and refuses new connections until AcceptClients is called.
 func (m *MockOBSServer) DisconnectClients() {
 	m.disconnectMu.Lock()
 	m.disconnectClient = true
@@ -85,16 +248,36 @@ func (m *MockOBSServer) DisconnectClients() {
 	m.clientsMu.Lock()
 	defer m.clientsMu.Unlock()
 	for _, client := range m.clients {
-		client.Close()
+		client.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server stopping"),
+			time.Now().Add(time.Second),
+		)
 	}
 	m.clients = nil
 }
 
+func (m *MockOBSServer) AcceptClients() {
+	m.disconnectMu.Lock()
+	defer m.disconnectMu.Unlock()
+	m.disconnectClient = false
+}
+
 func (m *MockOBSServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	m.disconnectMu.RLock()
+	refuse := m.disconnectClient
+	m.disconnectMu.RUnlock()
+
+	if refuse {
+		http.Error(w, "OBS is not running", http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := m.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
+	defer conn.Close()
 
 	m.clientsMu.Lock()
 	m.clients = append(m.clients, conn)
@@ -103,15 +286,6 @@ func (m *MockOBSServer) handleWebSocket(w http.ResponseWriter, r *http.Request)
 	m.sendHello(conn)
 
 	for {
-		m.disconnectMu.RLock()
-		shouldDisconnect := m.disconnectClient
-		m.disconnectMu.RUnlock()
-
-		if shouldDisconnect {
-			conn.Close()
-			return
-		}
-
 		_, message, err := conn.ReadMessage()
 		if err != nil {
 			return
@@ -135,7 +309,7 @@ func (m *MockOBSServer) sendHello(conn *websocket.Conn) {
 			"authentication":      map[string]interface{}{},
 		},
 	}
-	conn.WriteJSON(hello)
+	m.writeJSON(conn, hello)
 }
 
 func (m *MockOBSServer) handleRequest(conn *websocket.Conn, request map[string]interface{}) {
@@ -174,6 +348,14 @@ func (m *MockOBSServer) handleRequest(conn *websocket.Conn, request map[string]i
 		responseData = m.getStatsResponse()
 	case "GetStreamStatus":
 		responseData = m.getStreamStatusResponse()
+	case "GetOutputList":
+		responseData = m.getOutputListResponse()
+	case "GetOutputStatus":
+		requestData, _ := d["requestData"].(map[string]interface{})
+		outputName, _ := requestData["outputName"].(string)
+		responseData = m.getOutputStatusResponse(outputName)
+	case "GetRecordStatus":
+		responseData = m.getRecordStatusResponse()
 	case "GetStreamServiceSettings":
 		responseData = m.getStreamServiceSettingsResponse()
 	}
@@ -188,7 +370,7 @@ func (m *MockOBSServer) handleRequest(conn *websocket.Conn, request map[string]i
 		},
 	}
 
-	conn.WriteJSON(response)
+	m.writeJSON(conn, response)
 }
 
 func (m *MockOBSServer) sendIdentified(conn *websocket.Conn) {
@@ -198,7 +380,7 @@ func (m *MockOBSServer) sendIdentified(conn *websocket.Conn) {
 			"negotiatedRpcVersion": 1,
 		},
 	}
-	conn.WriteJSON(response)
+	m.writeJSON(conn, response)
 }
 
 func (m *MockOBSServer) getVersionResponse() map[string]interface{} {
@@ -239,21 +421,78 @@ func (m *MockOBSServer) getStreamStatusResponse() map[string]interface{} {
 
 	return map[string]interface{}{
 		"outputActive":        active,
-		"outputReconnecting":  false,
+		"outputReconnecting":  m.reconnecting,
 		"outputTimecode":      "00:10:30",
 		"outputDuration":      630000.0,
-		"outputCongestion":    0.0,
+		"outputCongestion":    m.congestion,
 		"outputBytes":         m.outputBytes,
 		"outputSkippedFrames": m.skippedFrames,
 		"outputTotalFrames":   m.totalFrames,
 	}
 }
 
+func (m *MockOBSServer) getOutputListResponse() map[string]interface{} {
+	m.statsMu.RLock()
+	defer m.statsMu.RUnlock()
+
+	outputs := []interface{}{}
+	for _, name := range m.outputOrder {
+		outputs = append(outputs, map[string]interface{}{
+			"outputName":   name,
+			"outputKind":   "rtmp_output",
+			"outputWidth":  1920,
+			"outputHeight": 1080,
+			"outputActive": m.outputs[name].Active,
+			"outputFlags": map[string]interface{}{
+				"OBS_OUTPUT_AUDIO":       true,
+				"OBS_OUTPUT_VIDEO":       true,
+				"OBS_OUTPUT_ENCODED":     true,
+				"OBS_OUTPUT_MULTI_TRACK": false,
+				"OBS_OUTPUT_SERVICE":     true,
+			},
+		})
+	}
+	return map[string]interface{}{"outputs": outputs}
+}
+
+func (m *MockOBSServer) getOutputStatusResponse(name string) map[string]interface{} {
+	m.statsMu.RLock()
+	defer m.statsMu.RUnlock()
+
+	output := m.outputs[name]
+	return map[string]interface{}{
+		"outputActive":        output.Active,
+		"outputReconnecting":  output.Reconnecting,
+		"outputTimecode":      "00:01:00",
+		"outputDuration":      60000.0,
+		"outputCongestion":    output.Congestion,
+		"outputBytes":         output.Bytes,
+		"outputSkippedFrames": output.SkippedFrames,
+		"outputTotalFrames":   output.Frames,
+	}
+}
+
+func (m *MockOBSServer) getRecordStatusResponse() map[string]interface{} {
+	m.statsMu.RLock()
+	defer m.statsMu.RUnlock()
+
+	return map[string]interface{}{
+		"outputActive":   m.recordActive,
+		"outputPaused":   false,
+		"outputTimecode": "00:00:05",
+		"outputDuration": 5000.0,
+		"outputBytes":    m.recordBytes,
+	}
+}
+
 func (m *MockOBSServer) getStreamServiceSettingsResponse() map[string]interface{} {
+	m.streamServerMu.RLock()
+	defer m.streamServerMu.RUnlock()
+
 	return map[string]interface{}{
 		"streamServiceType": "rtmp_common",
 		"streamServiceSettings": map[string]interface{}{
-			"server": "rtmp://test-ingest.example.com/app",
+			"server": m.streamServer,
 			"key":    "test-stream-key",
 		},
 	}
@@ -268,7 +507,12 @@ func (m *MockOBSServer) SendExitStartedEvent(conn *websocket.Conn) error {
 			"eventData":   map[string]interface{}{},
 		},
 	}
-	return conn.WriteJSON(event)
+	return m.writeJSON(conn, event)
+}
+
+// BroadcastExitStarted sends the ExitStarted event to all clients, as OBS does before it shuts down
+func (m *MockOBSServer) BroadcastExitStarted() {
+	m.broadcastEvent("ExitStarted", map[string]interface{}{})
 }
 
 func (m *MockOBSServer) ActiveClientCount() int {
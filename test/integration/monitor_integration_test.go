@@ -2,13 +2,27 @@ package integration
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/joepadmiraal/obs-monitor/internal/alert"
+	"github.com/joepadmiraal/obs-monitor/internal/metric"
 	"github.com/joepadmiraal/obs-monitor/internal/monitor"
+	"github.com/joepadmiraal/obs-monitor/internal/summary"
+	"github.com/joepadmiraal/obs-monitor/internal/webhook"
+	"go.uber.org/goleak"
 )
 
 func TestMonitor_Integration_BasicFlow(t *testing.T) {
@@ -40,6 +54,11 @@ func TestMonitor_Integration_BasicFlow(t *testing.T) {
 
 	time.Sleep(500 * time.Millisecond)
 
+	// The systemd watchdog is only notified while rows are written
+	if since := time.Since(mon.LastWrite()); since > 300*time.Millisecond {
+		t.Errorf("Expected a row to be written within the last writer interval, last write %v ago", since)
+	}
+
 	mon.Shutdown()
 	select {
 	case <-mon.Done():
@@ -194,6 +213,80 @@ func TestMonitor_Integration_GracefulShutdown(t *testing.T) {
 	mon.Close()
 }
 
+func TestMonitor_Integration_NoGoroutineLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	// Restart the monitor like a long-lived service embedding it would
+	for i := 0; i < 3; i++ {
+		connInfo := monitor.ObsConnectionInfo{
+			Password:         "",
+			Host:             host,
+			CSVFile:          filepath.Join(t.TempDir(), "test-metrics.csv"),
+			Outputs:          []string{"jsonl:" + filepath.Join(t.TempDir(), "test-metrics.jsonl")},
+			PrometheusListen: "127.0.0.1:0",
+			MetricInterval:   50,
+			WriterInterval:   100,
+		}
+
+		mon, err := monitor.NewMonitor(connInfo)
+		if err != nil {
+			t.Fatalf("Failed to create monitor: %v", err)
+		}
+
+		if err := mon.Start(); err != nil {
+			t.Fatalf("Failed to start monitor: %v", err)
+		}
+
+		time.Sleep(300 * time.Millisecond)
+
+		mon.Shutdown()
+		select {
+		case <-mon.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Monitor did not shut down within timeout")
+		}
+		mon.Close()
+	}
+}
+
+func TestMonitor_Integration_FailedStartDoesNotLeak(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Password:       "",
+		Host:           host,
+		Outputs:        []string{"unknown:target"},
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err == nil {
+		t.Fatal("Expected error for unknown output kind")
+	}
+
+	select {
+	case <-mon.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Monitor did not stop its goroutines after a failed start")
+	}
+	mon.Close()
+}
+
 func TestMonitor_Integration_ContextCancellation(t *testing.T) {
 	mockServer := NewMockOBSServer()
 	defer mockServer.Close()
@@ -234,7 +327,6 @@ func TestMonitor_Integration_ContextCancellation(t *testing.T) {
 }
 
 func TestMonitor_Integration_OBSDisconnection(t *testing.T) {
-	t.Skip("Skipping OBS disconnection test - requires fix in goobs client disconnect detection")
 	mockServer := NewMockOBSServer()
 	defer mockServer.Close()
 
@@ -264,13 +356,78 @@ func TestMonitor_Integration_OBSDisconnection(t *testing.T) {
 
 	mockServer.DisconnectClients()
 
+	// Pending requests only fail after the response timeout, so detection takes a few seconds
+	deadline := time.Now().Add(10 * time.Second)
+	for !slices.Contains(readColumn(t, csvFile, "obs_connected"), "false") {
+		if time.Now().After(deadline) {
+			t.Fatal("Monitor did not write disconnected rows")
+		}
+		select {
+		case <-mon.Done():
+			t.Fatal("Monitor exited on disconnection instead of reconnecting")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	mockServer.AcceptClients()
+
+	deadline = time.Now().Add(5 * time.Second)
+	for mockServer.ActiveClientCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Monitor did not reconnect to OBS")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	mon.Shutdown()
 	select {
 	case <-mon.Done():
-	case <-time.After(5 * time.Second):
-		t.Fatal("Monitor did not detect disconnection")
+	case <-time.After(2 * time.Second):
+		t.Fatal("Monitor did not shut down in time")
 	}
-
 	mon.Close()
+
+	states := readColumn(t, csvFile, "obs_connected")
+	disconnectedAt := slices.Index(states, "false")
+	if disconnectedAt == -1 {
+		t.Fatalf("Expected disconnected rows while OBS was away, got %v", states)
+	}
+	if !slices.Contains(states[disconnectedAt:], "true") {
+		t.Errorf("Expected connected rows after reconnecting, got %v", states)
+	}
+}
+
+func readColumn(t *testing.T, csvFile, column string) []string {
+	t.Helper()
+
+	f, err := os.Open(csvFile)
+	if err != nil {
+		t.Fatalf("Failed to open CSV file: %v", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV file: %v", err)
+	}
+	if len(records) < 2 {
+		t.Fatalf("Expected metadata and header lines, got %d lines", len(records))
+	}
+
+	index := slices.Index(records[1], column)
+	if index == -1 {
+		t.Fatalf("Column %s not found in header %v", column, records[1])
+	}
+
+	values := []string{}
+	for _, record := range records[2:] {
+		values = append(values, record[index])
+	}
+	return values
 }
 
 func TestMonitor_Integration_ConcurrentMetricCollection(t *testing.T) {
@@ -527,3 +684,1204 @@ func TestMonitor_Integration_FullMonitoringCycle(t *testing.T) {
 		}
 	}
 }
+
+func TestMonitor_Integration_MultipleOutputs(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	tmpDir := t.TempDir()
+	firstFile := filepath.Join(tmpDir, "first.csv")
+	secondFile := filepath.Join(tmpDir, "second.csv")
+
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Password:       "",
+		Host:           host,
+		Outputs:        []string{"csv:" + firstFile, "csv:" + secondFile},
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Monitor did not shut down in time")
+	}
+	mon.Close()
+
+	for _, file := range []string{firstFile, secondFile} {
+		if rows := readColumn(t, file, "timestamp"); len(rows) == 0 {
+			t.Errorf("Expected metrics rows in %s", file)
+		}
+	}
+}
+
+func TestMonitor_Integration_InvalidOutput(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Password:       "",
+		Host:           host,
+		Outputs:        []string{"unknown:target"},
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+	defer mon.Close()
+
+	if err := mon.Start(); err == nil {
+		t.Fatal("Expected error for unknown output kind")
+	}
+}
+
+func TestMonitor_Integration_Aggregations(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Password:       "",
+		Host:           host,
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 250,
+		Aggregations:   []metric.Aggregation{metric.AggregateP95, metric.AggregateCount},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(800 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	for _, column := range []string{"obs_rtt_ms_p95", "obs_rtt_ms_count", "obs_cpu_percent_p95", "system_cpu_percent_p95"} {
+		readColumn(t, csvFile, column)
+	}
+
+	counts := readColumn(t, csvFile, "obs_cpu_percent_count")
+	if len(counts) == 0 {
+		t.Fatal("Expected metrics rows")
+	}
+	if counts[len(counts)-1] == "0" || counts[len(counts)-1] == "" {
+		t.Errorf("Expected several OBS stats measurements per writer interval, got %v", counts)
+	}
+}
+
+func TestMonitor_Integration_TCPProbe(t *testing.T) {
+	ingest, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ingest.Close()
+
+	go func() {
+		for {
+			conn, err := ingest.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+	mockServer.SetStreamServer("rtmp://" + ingest.Addr().String() + "/app")
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Password:       "",
+		Host:           host,
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 250,
+		Probe:          monitor.ProbeTCP,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(800 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	connects := readColumn(t, csvFile, "obs_tcp_connect_ms")
+	if len(connects) == 0 || connects[len(connects)-1] == "" {
+		t.Errorf("Expected TCP connect times, got %v", connects)
+	}
+
+	failures := readColumn(t, csvFile, "obs_tcp_failure_pct")
+	if failures[len(failures)-1] != "0.00" {
+		t.Errorf("Expected no failed handshakes, got %v", failures)
+	}
+
+	// The Google pinger keeps running
+	readColumn(t, csvFile, "google_rtt_ms")
+}
+
+func TestMonitor_Integration_PingTargets(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Password:       "",
+		Host:           host,
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 250,
+		PingTargets: []monitor.PingTarget{
+			{Name: "gateway", Host: "127.0.0.1"},
+			{Name: "dns", Host: "localhost"},
+		},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(600 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	for _, column := range []string{"obs_rtt_ms", "gateway_rtt_ms", "gateway_loss_pct", "dns_rtt_ms", "dns_jitter_ms"} {
+		readColumn(t, csvFile, column)
+	}
+
+	f, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	if strings.Contains(string(f), "google_rtt_ms") {
+		t.Error("Expected the default Google target to be replaced by the configured targets")
+	}
+}
+
+func TestMonitor_Integration_MultipleInstances(t *testing.T) {
+	mainServer := NewMockOBSServer()
+	defer mainServer.Close()
+	mainServer.SetStats(20, 512)
+
+	backupServer := NewMockOBSServer()
+	defer backupServer.Close()
+	backupServer.SetStats(40, 1024)
+	backupServer.SetStreamServer("rtmp://backup-ingest.example.com/app")
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+
+	connInfo := monitor.ObsConnectionInfo{
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 250,
+		Instances: []monitor.Instance{
+			{Name: "main", Host: strings.Replace(mainServer.URL(), "ws://", "", 1)},
+			{Name: "backup", Host: strings.Replace(backupServer.URL(), "ws://", "", 1)},
+		},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(800 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	content, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	if !strings.Contains(string(content), "Stream domain: main=test-ingest.example.com, backup=backup-ingest.example.com") {
+		t.Errorf("Expected the stream domains of both instances in the metadata, got:\n%s", content)
+	}
+
+	instances := readColumn(t, csvFile, "instance")
+	cpuUsages := readColumn(t, csvFile, "obs_cpu_percent")
+	systemCpuUsages := readColumn(t, csvFile, "system_cpu_percent")
+	if len(instances) < 4 || len(instances)%2 != 0 {
+		t.Fatalf("Expected a row per instance per interval, got instances %v", instances)
+	}
+
+	for i := 0; i < len(instances); i += 2 {
+		if instances[i] != "main" || instances[i+1] != "backup" {
+			t.Errorf("Expected rows of main and backup, got %s and %s", instances[i], instances[i+1])
+		}
+		if cpuUsages[i] != "20.00" || cpuUsages[i+1] != "40.00" {
+			t.Errorf("Expected the OBS CPU usage of each instance, got %s and %s", cpuUsages[i], cpuUsages[i+1])
+		}
+		if systemCpuUsages[i] != systemCpuUsages[i+1] {
+			t.Errorf("Expected both rows to share the system metrics, got %s and %s", systemCpuUsages[i], systemCpuUsages[i+1])
+		}
+	}
+}
+
+// instanceColumn returns the values of a column in the rows of an instance
+func instanceColumn(t *testing.T, csvFile, instance, column string) []string {
+	t.Helper()
+
+	instances := readColumn(t, csvFile, "instance")
+	values := []string{}
+	for i, value := range readColumn(t, csvFile, column) {
+		if instances[i] == instance {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func TestMonitor_Integration_WaitForOBS(t *testing.T) {
+	mainServer := NewMockOBSServer()
+	defer mainServer.Close()
+
+	// Reserve a free port for the backup OBS, it is only started after the monitor
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve port: %v", err)
+	}
+	backupHost := listener.Addr().String()
+	listener.Close()
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+
+	connInfo := monitor.ObsConnectionInfo{
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 100,
+		Wait:           true,
+		Instances: []monitor.Instance{
+			{Name: "main", Host: strings.Replace(mainServer.URL(), "ws://", "", 1)},
+			{Name: "backup", Host: backupHost},
+		},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	// The instance that is not running does not keep the other one from being monitored
+	started := time.Now()
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+	if time.Since(started) > time.Second {
+		t.Errorf("Expected Start not to wait for the backup OBS, took %v", time.Since(started))
+	}
+
+	time.Sleep(700 * time.Millisecond)
+	if !slices.Contains(instanceColumn(t, csvFile, "main", "obs_connected"), "true") {
+		t.Error("Expected the main OBS to be monitored while the backup OBS is not running")
+	}
+	if backup := instanceColumn(t, csvFile, "backup", "obs_connected"); len(backup) == 0 || slices.Contains(backup, "true") {
+		t.Errorf("Expected disconnected rows of the backup OBS, got %v", backup)
+	}
+
+	backupServer, err := NewMockOBSServerOn(backupHost)
+	if err != nil {
+		t.Fatalf("Failed to start mock OBS server: %v", err)
+	}
+	defer backupServer.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(instanceColumn(t, csvFile, "backup", "obs_connected"), "true") {
+		if time.Now().After(deadline) {
+			t.Fatal("Monitor did not connect once the backup OBS was started")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	// The columns of the backup OBS were declared before it was started, so its values are written
+	cpu := instanceColumn(t, csvFile, "backup", "obs_cpu_percent")
+	if !slices.ContainsFunc(cpu, func(v string) bool { return v != "" }) {
+		t.Errorf("Expected OBS stats of the backup OBS once it was started, got %v", cpu)
+	}
+}
+
+func TestMonitor_Integration_WaitShutdown(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           "localhost:9999",
+		MetricInterval: 50,
+		WriterInterval: 100,
+		Wait:           true,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Expected Start to return while OBS is not running, got %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	mon.Shutdown()
+
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+}
+
+func TestMonitor_Integration_SessionFiles(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	sessionDir := t.TempDir()
+	jsonlFile := filepath.Join(t.TempDir(), "test-metrics.jsonl")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		Outputs:        []string{"session:" + sessionDir, "jsonl:" + jsonlFile},
+		MetricInterval: 50,
+		WriterInterval: 100,
+		SkipIdle:       true,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	mockServer.StartStream()
+	time.Sleep(500 * time.Millisecond)
+	mockServer.StopStream()
+	time.Sleep(300 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	files, err := filepath.Glob(filepath.Join(sessionDir, "obs-monitor-*.csv"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected a single session file, got %v", files)
+	}
+
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read session file: %v", err)
+	}
+	metadata, _, _ := strings.Cut(string(content), "\n")
+	if !strings.Contains(metadata, "Stream started: ") || !strings.Contains(metadata, "Stream stopped: ") {
+		t.Errorf("Expected the stream start and stop in the metadata, got %s", metadata)
+	}
+
+	// The rows right after the start may still hold the stream state of before the event
+	activeRows := readColumn(t, files[0], "stream_active")
+	if len(activeRows) < 2 || len(activeRows) > 7 || !slices.Contains(activeRows, "true") {
+		t.Errorf("Expected the rows of the live stream in the session file, got stream_active %v", activeRows)
+	}
+
+	// Idle rows are skipped in the other outputs too
+	lines, err := os.ReadFile(jsonlFile)
+	if err != nil {
+		t.Fatalf("Failed to read JSONL file: %v", err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(lines)), "\n")
+	if len(rows) == 0 || len(rows) > 7 {
+		t.Errorf("Expected only the rows of the live stream, got %d rows", len(rows))
+	}
+}
+
+func TestMonitor_Integration_SkipIdleKeepsPrometheus(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	// Reserve a free port for the Prometheus output
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve port: %v", err)
+	}
+	prometheusAddr := listener.Addr().String()
+	listener.Close()
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:             strings.Replace(mockServer.URL(), "ws://", "", 1),
+		CSVFile:          csvFile,
+		PrometheusListen: prometheusAddr,
+		MetricInterval:   50,
+		WriterInterval:   100,
+		SkipIdle:         true,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	mockServer.StartStream()
+	time.Sleep(400 * time.Millisecond)
+	if value := scrapeMetric(t, prometheusAddr, "obs_monitor_stream_active"); value != "1" {
+		t.Errorf("Expected the live stream in Prometheus, got obs_monitor_stream_active %q", value)
+	}
+
+	mockServer.StopStream()
+	time.Sleep(400 * time.Millisecond)
+	if value := scrapeMetric(t, prometheusAddr, "obs_monitor_stream_active"); value != "0" {
+		t.Errorf("Expected Prometheus to get the idle rows, got obs_monitor_stream_active %q", value)
+	}
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	// The CSV file only holds the rows of the live stream
+	activeRows := readColumn(t, csvFile, "stream_active")
+	if len(activeRows) == 0 || len(activeRows) > 7 {
+		t.Errorf("Expected only the rows of the live stream in the CSV file, got stream_active %v", activeRows)
+	}
+}
+
+// scrapeMetric returns the value of an unlabeled metric served by the Prometheus output
+func scrapeMetric(t *testing.T, addr, name string) string {
+	t.Helper()
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if value, ok := strings.CutPrefix(line, name+" "); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestMonitor_Integration_SessionSummary(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+	mockServer.SetStreamActive(true)
+	mockServer.SetStats(30, 512)
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(csvFile), "test-metrics.summary.json"))
+	if err != nil {
+		t.Fatalf("Expected a summary next to the CSV file: %v", err)
+	}
+
+	var report summary.Summary
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to decode summary: %v", err)
+	}
+	if report.DurationSeconds <= 0 || len(report.Instances) != 1 {
+		t.Fatalf("Expected a summary of a single instance, got %+v", report)
+	}
+
+	inst := report.Instances[0]
+	if inst.LiveSeconds <= 0 || inst.PeakObsCPUPercent != 30 {
+		t.Errorf("Expected the live time and OBS CPU peak, got %+v", inst)
+	}
+	if _, ok := inst.RTT["obs"]; !ok {
+		t.Errorf("Expected RTT statistics of the stream server, got %v", inst.RTT)
+	}
+	if _, ok := inst.RTT["google"]; !ok {
+		t.Errorf("Expected RTT statistics of the ping targets, got %v", inst.RTT)
+	}
+}
+
+func TestMonitor_Integration_StreamHealth(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+	mockServer.SetStreamActive(true)
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	jsonlFile := filepath.Join(t.TempDir(), "test-metrics.jsonl")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		CSVFile:        csvFile,
+		Outputs:        []string{"jsonl:" + jsonlFile},
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	mockServer.SetStreamHealth(0.8, true)
+	time.Sleep(200 * time.Millisecond)
+	mockServer.SetStreamHealth(0.1, false)
+	time.Sleep(200 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	lines, err := os.ReadFile(jsonlFile)
+	if err != nil {
+		t.Fatalf("Failed to read JSONL file: %v", err)
+	}
+
+	congested, reconnecting, duration := false, false, false
+	for _, line := range strings.Split(strings.TrimSpace(string(lines)), "\n") {
+		var row map[string]any
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("Failed to decode row: %v", err)
+		}
+		if row["output_congestion"] == 0.8 {
+			congested = true
+		}
+		if row["output_reconnecting"] == true {
+			reconnecting = true
+		}
+		if row["output_duration"] == 630.0 {
+			duration = true
+		}
+	}
+	if !congested || !reconnecting || !duration {
+		t.Errorf("Expected a congested and reconnecting row with the stream duration, got:\n%s", lines)
+	}
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(csvFile), "test-metrics.summary.json"))
+	if err != nil {
+		t.Fatalf("Expected a summary next to the CSV file: %v", err)
+	}
+	var report summary.Summary
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to decode summary: %v", err)
+	}
+	if report.Instances[0].StreamReconnects != 1 {
+		t.Errorf("Expected 1 stream reconnect, got %d", report.Instances[0].StreamReconnects)
+	}
+}
+
+func TestMonitor_Integration_Recording(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	jsonlFile := filepath.Join(t.TempDir(), "test-metrics.jsonl")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		Outputs:        []string{"jsonl:" + jsonlFile},
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	mockServer.StartRecord()
+	for i := 0; i < 4; i++ {
+		mockServer.IncrementRecordBytes(250_000)
+		time.Sleep(100 * time.Millisecond)
+	}
+	mockServer.StopRecord()
+	time.Sleep(300 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	lines, err := os.ReadFile(jsonlFile)
+	if err != nil {
+		t.Fatalf("Failed to read JSONL file: %v", err)
+	}
+
+	var recording bool
+	var bytes float64
+	for _, line := range strings.Split(strings.TrimSpace(string(lines)), "\n") {
+		var row map[string]any
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("Failed to decode row: %v", err)
+		}
+		if row["record_active"] == true {
+			recording = true
+		}
+		if b, ok := row["record_bytes"].(float64); ok {
+			bytes += b
+		}
+	}
+	if !recording {
+		t.Errorf("Expected rows of the recording, got:\n%s", lines)
+	}
+	if bytes <= 0 || bytes > 1_000_000 {
+		t.Errorf("Expected the bytes of the recording, got %v", bytes)
+	}
+	if last := strings.Split(strings.TrimSpace(string(lines)), "\n"); !strings.Contains(last[len(last)-1], `"record_active":false`) {
+		t.Errorf("Expected the recording to be stopped in the last row, got %s", last[len(last)-1])
+	}
+}
+
+func TestMonitor_Integration_EventLog(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "test-metrics.csv")
+	eventsFile := filepath.Join(tmpDir, "events.jsonl")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		Outputs:        []string{"csv:" + csvFile, "events:" + eventsFile},
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	mockServer.StartStream()
+	time.Sleep(50 * time.Millisecond)
+	mockServer.SetProgramScene("BRB")
+	time.Sleep(300 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	lines, err := os.ReadFile(eventsFile)
+	if err != nil {
+		t.Fatalf("Failed to read event log: %v", err)
+	}
+
+	messages := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(lines)), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, event["timestamp"].(string)); err != nil {
+			t.Errorf("Expected a timestamp, got %v", event)
+		}
+		messages = append(messages, event["message"].(string))
+	}
+	if !slices.Equal(messages, []string{"stream started", "scene BRB"}) {
+		t.Errorf("Expected the stream start and the scene change, got %v", messages)
+	}
+
+	rows := slices.DeleteFunc(readColumn(t, csvFile, "events"), func(s string) bool { return s == "" })
+	if events := strings.Join(rows, "; "); events != "stream started; scene BRB" {
+		t.Errorf("Expected the events in the rows of their window, got %q", events)
+	}
+}
+
+func TestMonitor_Integration_AudioLevels(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 100,
+		Audio: monitor.Audio{
+			Inputs:             []string{"Mic/Aux"},
+			SilenceThresholdDB: -50,
+			SilenceDuration:    200 * time.Millisecond,
+		},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	// OBS sends the levels every 50ms
+	send := func(level float64, d time.Duration) {
+		for end := time.Now().Add(d); time.Now().Before(end); {
+			mockServer.SendVolumeMeters(map[string]float64{"Mic/Aux": level, "Desktop Audio": 1})
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	send(0.5, 300*time.Millisecond)
+	send(0, 500*time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	if peaks := readColumn(t, csvFile, "audio_mic_aux_peak_db"); !slices.Contains(peaks, "-6.0") || !slices.Contains(peaks, "-100.0") {
+		t.Errorf("Expected the loud and the silent levels, got %v", peaks)
+	}
+	silent := readColumn(t, csvFile, "audio_mic_aux_silent")
+	if silent[0] != "false" || silent[len(silent)-1] != "true" {
+		t.Errorf("Expected the input to become silent, got %v", silent)
+	}
+}
+
+type recordingNotifier struct {
+	alerts []alert.Alert
+	mu     sync.Mutex
+}
+
+func (n *recordingNotifier) Notify(a alert.Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, a)
+	return nil
+}
+
+func TestMonitor_Integration_Alerts(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "test-metrics.csv")
+	eventsFile := filepath.Join(tmpDir, "events.jsonl")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+	notifier := &recordingNotifier{}
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		Outputs:        []string{"csv:" + csvFile, "events:" + eventsFile},
+		MetricInterval: 50,
+		WriterInterval: 100,
+		Alerts: []alert.Rule{
+			{Name: "high-cpu", Metric: "obs_cpu_percent", Operator: ">", Threshold: 90, ForIntervals: 2, Severity: "critical"},
+		},
+		Notifiers: []alert.Notifier{notifier},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	mockServer.SetStats(95, 256)
+	time.Sleep(500 * time.Millisecond)
+	mockServer.SetStats(10, 256)
+	time.Sleep(300 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if len(notifier.alerts) != 2 || notifier.alerts[0].State != alert.Firing || notifier.alerts[1].State != alert.Resolved {
+		t.Fatalf("Expected the rule to fire and resolve once, got %+v", notifier.alerts)
+	}
+	if notifier.alerts[0].Severity != "critical" || notifier.alerts[0].Value != 95 {
+		t.Errorf("Unexpected firing alert %+v", notifier.alerts[0])
+	}
+
+	rows := slices.DeleteFunc(readColumn(t, csvFile, "events"), func(s string) bool { return s == "" })
+	if !slices.Equal(rows, []string{"alert high-cpu firing: obs_cpu_percent 95 > 90", "alert high-cpu resolved: obs_cpu_percent 10"}) {
+		t.Errorf("Expected the alerts in the events column, got %v", rows)
+	}
+
+	events, err := os.ReadFile(eventsFile)
+	if err != nil {
+		t.Fatalf("Failed to read event log: %v", err)
+	}
+	if strings.Count(string(events), `"type":"Alert"`) != 2 {
+		t.Errorf("Expected both alerts in the event log, got %s", events)
+	}
+}
+
+func TestMonitor_Integration_Alerts_SharedColumns(t *testing.T) {
+	mainServer := NewMockOBSServer()
+	defer mainServer.Close()
+	backupServer := NewMockOBSServer()
+	defer backupServer.Close()
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	notifier := &recordingNotifier{}
+
+	connInfo := monitor.ObsConnectionInfo{
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 100,
+		Instances: []monitor.Instance{
+			{Name: "main", Host: strings.Replace(mainServer.URL(), "ws://", "", 1)},
+			{Name: "backup", Host: strings.Replace(backupServer.URL(), "ws://", "", 1)},
+		},
+		Alerts: []alert.Rule{
+			{Name: "any-memory", Metric: "system_memory_percent", Operator: ">=", Threshold: 0},
+			{Name: "any-obs-memory", Metric: "obs_memory_mb", Operator: ">=", Threshold: 0},
+		},
+		Notifiers: []alert.Notifier{notifier},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	// The rule of a shared column fires once, the rule of an instance column once per instance
+	fired := map[string][]string{}
+	for _, a := range notifier.alerts {
+		fired[a.Rule] = append(fired[a.Rule], a.Instance)
+	}
+	if !slices.Equal(fired["any-memory"], []string{""}) {
+		t.Errorf("Expected the shared rule to fire once, got instances %q", fired["any-memory"])
+	}
+	slices.Sort(fired["any-obs-memory"])
+	if !slices.Equal(fired["any-obs-memory"], []string{"backup", "main"}) {
+		t.Errorf("Expected the instance rule to fire for both instances, got %q", fired["any-obs-memory"])
+	}
+
+	// The rows of every instance show the alert of the shared column
+	for _, instance := range []string{"main", "backup"} {
+		events := strings.Join(instanceColumn(t, csvFile, instance, "events"), " ")
+		if !strings.Contains(events, "alert any-memory firing") {
+			t.Errorf("Expected the shared alert in the rows of %s, got %q", instance, events)
+		}
+	}
+}
+
+func TestMonitor_Integration_Webhooks(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+
+	var (
+		payloads []map[string]any
+		mu       sync.Mutex
+	)
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode webhook payload: %v", err)
+		}
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer endpoint.Close()
+
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		MetricInterval: 50,
+		WriterInterval: 100,
+		Alerts: []alert.Rule{
+			{Name: "high-cpu", Metric: "obs_cpu_percent", Operator: ">", Threshold: 90},
+		},
+		Webhooks: []webhook.Endpoint{
+			{URL: endpoint.URL, Timeout: time.Second, RateLimit: webhook.DefaultRateLimit},
+		},
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	mockServer.StartStream()
+	mockServer.SetStats(95, 256)
+	time.Sleep(300 * time.Millisecond)
+	mockServer.StopStream()
+	time.Sleep(200 * time.Millisecond)
+
+	// OBS announces that it exits, the monitor disconnects and reconnects once OBS is back
+	mockServer.BroadcastExitStarted()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		received := len(payloads)
+		mu.Unlock()
+		if received >= 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Monitor did not report the reconnection")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var types, messages []string
+	for _, payload := range payloads {
+		types = append(types, payload["type"].(string))
+		messages = append(messages, payload["message"].(string))
+	}
+	if !slices.Equal(types, []string{"stream_started", "alert", "stream_stopped", "disconnected", "reconnected"}) {
+		t.Fatalf("Unexpected webhook payloads %v", messages)
+	}
+	if messages[1] != "Alert high-cpu firing: obs_cpu_percent 95 > 90" {
+		t.Errorf("Unexpected alert message %s", messages[1])
+	}
+	if messages[3] != "OBS exited" || payloads[3]["severity"] != "warning" {
+		t.Errorf("Expected OBS to exit, got %v", payloads[3])
+	}
+}
+
+func TestMonitor_Integration_Outputs(t *testing.T) {
+	mockServer := NewMockOBSServer()
+	defer mockServer.Close()
+	mockServer.SetOutput("adv_stream", MockOutput{})
+	mockServer.SetOutput("Aitum Multi Output 1", MockOutput{})
+
+	csvFile := filepath.Join(t.TempDir(), "test-metrics.csv")
+	host := strings.Replace(mockServer.URL(), "ws://", "", 1)
+
+	connInfo := monitor.ObsConnectionInfo{
+		Host:           host,
+		CSVFile:        csvFile,
+		MetricInterval: 50,
+		WriterInterval: 100,
+	}
+
+	mon, err := monitor.NewMonitor(connInfo)
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+
+	if err := mon.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	for i := 1; i <= 4; i++ {
+		mockServer.SetOutput("Aitum Multi Output 1", MockOutput{Active: true, Bytes: float64(i) * 100_000, Frames: float64(i) * 6, Congestion: 0.4})
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	mon.Shutdown()
+	select {
+	case <-mon.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("Monitor did not shut down within timeout")
+	}
+	mon.Close()
+
+	if active := readColumn(t, csvFile, "output_adv_stream_active"); !slices.Contains(active, "false") || slices.Contains(active, "true") {
+		t.Errorf("Expected adv_stream to stay inactive, got %v", active)
+	}
+
+	var bytes float64
+	for _, value := range readColumn(t, csvFile, "output_aitum_multi_output_1_bytes") {
+		if value != "" {
+			b, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("Failed to parse bytes %q: %v", value, err)
+			}
+			bytes += b
+		}
+	}
+	if bytes <= 0 || bytes > 400_000 {
+		t.Errorf("Expected the bytes of the multistream output, got %v", bytes)
+	}
+	if congestion := readColumn(t, csvFile, "output_aitum_multi_output_1_congestion"); !slices.Contains(congestion, "0.40") {
+		t.Errorf("Expected the congestion of the multistream output, got %v", congestion)
+	}
+}